@@ -0,0 +1,61 @@
+package kdl
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Prop converts a property of the node into a Go value of type T,
+// following the same rules as Unmarshal.
+//
+// Returns an error wrapping ErrMissingField if the node has no such property,
+// or ErrCannotUnmarshal if the property cannot be stored in a T.
+func Prop[T any](n *Node, key Identifier) (T, error) {
+
+	if !n.HasProp(key) {
+		var zero T
+		return zero, fmt.Errorf("%w: property %q of node %q", ErrMissingField, key, n.Name)
+	}
+
+	res, err := valueAs[T](n.Props[key])
+	if err != nil {
+		return res, fmt.Errorf("property %q of node %q: %w", key, n.Name, err)
+	}
+	return res, nil
+}
+
+// PropOr converts a property of the node into a Go value of type T,
+// or returns the fallback value if the node has no such property.
+// See Prop for details.
+func PropOr[T any](n *Node, key Identifier, fallback T) (T, error) {
+	if !n.HasProp(key) {
+		return fallback, nil
+	}
+	return Prop[T](n, key)
+}
+
+// Arg converts an argument of the node into a Go value of type T,
+// following the same rules as Unmarshal.
+//
+// Returns an error wrapping ErrMissingField if the node has less arguments,
+// or ErrCannotUnmarshal if the argument cannot be stored in a T.
+func Arg[T any](n *Node, index int) (T, error) {
+
+	if index < 0 || index >= len(n.Args) {
+		var zero T
+		return zero, fmt.Errorf("%w: argument %d of node %q", ErrMissingField, index, n.Name)
+	}
+
+	res, err := valueAs[T](n.Args[index])
+	if err != nil {
+		return res, fmt.Errorf("argument %d of node %q: %w", index, n.Name, err)
+	}
+	return res, nil
+}
+
+// valueAs converts a Value into a new Go value of type T.
+func valueAs[T any](val Value) (T, error) {
+	var res T
+	err := kdlValueToValue(val, reflect.ValueOf(&res).Elem())
+	return res, err
+}
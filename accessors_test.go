@@ -0,0 +1,79 @@
+package kdl
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedAccessors(t *testing.T) {
+
+	doc, err := ParseString(`server "example.com" 443 port=8080 ratio=0.5 tls=true timeout=(duration)"PT30S" name="main"`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	n := &doc.Nodes[0]
+
+	port, err := Prop[uint16](n, "port")
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(8080), port)
+
+	ratio, err := Prop[float64](n, "ratio")
+	assert.NoError(t, err)
+	assert.Equal(t, 0.5, ratio)
+
+	tls, err := Prop[bool](n, "tls")
+	assert.NoError(t, err)
+	assert.True(t, tls)
+
+	timeout, err := Prop[time.Duration](n, "timeout")
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, timeout)
+
+	host, err := Arg[string](n, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "example.com", host)
+
+	secure, err := Arg[int](n, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 443, secure)
+
+	retries, err := PropOr(n, "retries", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, retries)
+
+	port, err = PropOr(n, "port", uint16(80))
+	assert.NoError(t, err)
+	assert.Equal(t, uint16(8080), port)
+}
+
+func TestTypedAccessorsReportErrors(t *testing.T) {
+
+	doc, err := ParseString(`server 1 port=70000 name="main"`)
+	if !assert.NoError(t, err) {
+		return
+	}
+	n := &doc.Nodes[0]
+
+	_, err = Prop[uint16](n, "port")
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+	assert.ErrorContains(t, err, `property "port" of node "server"`)
+
+	_, err = Prop[int](n, "name")
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+	assert.ErrorContains(t, err, "string into Go value of type int")
+
+	_, err = Prop[int](n, "missing")
+	assert.ErrorIs(t, err, ErrMissingField)
+
+	_, err = Arg[string](n, 0)
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+
+	_, err = Arg[int](n, 1)
+	assert.ErrorIs(t, err, ErrMissingField)
+	assert.ErrorContains(t, err, `argument 1 of node "server"`)
+
+	_, err = PropOr(n, "port", int8(0))
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+}
@@ -0,0 +1,79 @@
+package kdl
+
+import "strings"
+
+// Comments are the comments attached to a Node or a Document.
+//
+// Each comment is kept with its delimiters, eg. "// http" or "/* ports */".
+// When written, a comment without delimiters becomes a single-line comment,
+// with every line prefixed by "// ". An empty string stands for a blank line.
+//
+// Comments after the last child of a node, and within slashdashed nodes, are not kept.
+// A SyntaxTree keeps every comment of a document.
+type Comments struct {
+	// Before a node, these are the comments on the lines just above it.
+	// In a document, these are the header comments, separated from the first node by a blank line.
+	Before []string
+	// After a node, these are the comments on the same line as its end.
+	// In a document, these are the comments after its last node.
+	After []string
+}
+
+// IsEmpty checks if there are no comments.
+func (c *Comments) IsEmpty() bool {
+	return len(c.Before) == 0 && len(c.After) == 0
+}
+
+// onlyBlankLines checks if there are no comments other than blank lines.
+func onlyBlankLines(comments []string) bool {
+	for _, c := range comments {
+		if c != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// attachDocumentComments moves the header comments, separated from the first node by a blank line,
+// and the comments after the last node from the nodes to the Document.
+func attachDocumentComments(d *Document, footer []string) {
+
+	if len(d.Nodes) > 0 {
+		first := &d.Nodes[0].Comments
+		for i := len(first.Before) - 1; i >= 0; i-- {
+			if first.Before[i] == "" {
+				if i > 0 {
+					d.Comments.Before = first.Before[:i:i]
+				}
+				first.Before = first.Before[i+1:]
+				break
+			}
+		}
+		if len(first.Before) == 0 {
+			first.Before = nil
+		}
+	}
+
+	if onlyBlankLines(footer) {
+		return
+	}
+	for footer[len(footer)-1] == "" {
+		footer = footer[:len(footer)-1]
+	}
+	d.Comments.After = footer
+}
+
+// formatComment returns the text of a comment, as it is written.
+// Text without delimiters becomes a single-line comment on each of its lines.
+func formatComment(c string, indent string) string {
+
+	if strings.HasPrefix(c, "//") || strings.HasPrefix(c, "/*") {
+		return c
+	}
+
+	lines := strings.Split(c, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("// "+line, " ")
+	}
+	return strings.Join(lines, "\n"+indent)
+}
@@ -0,0 +1,94 @@
+package kdl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsesComments(t *testing.T) {
+
+	doc, err := ParseString(`// Server configuration
+// Generated, do not edit
+
+// The main one
+server "a" {
+    /* ports */
+    port 8080 // http
+
+    // later
+    host "x" /* inline */
+}
+/- skipped
+// after a skipped node
+last
+
+// footer
+`)
+
+	assert.NoError(t, err)
+	assert.Equal(t, Comments{
+		Before: []string{"// Server configuration", "// Generated, do not edit"},
+		After:  []string{"", "// footer"},
+	}, doc.Comments)
+
+	server := &doc.Nodes[0]
+	assert.Equal(t, []string{"// The main one"}, server.Comments.Before)
+	assert.Equal(t, []string{"/* ports */"}, server.Children[0].Comments.Before)
+	assert.Equal(t, []string{"// http"}, server.Children[0].Comments.After)
+	assert.Equal(t, []string{"", "// later"}, server.Children[1].Comments.Before)
+	assert.Equal(t, []string{"/* inline */"}, server.Children[1].Comments.After)
+	assert.Equal(t, []string{"// after a skipped node"}, doc.Nodes[1].Comments.Before)
+}
+
+func TestParsesNoCommentsForBlankLines(t *testing.T) {
+
+	doc, err := ParseString("\n\nfoo\n\nbar\n\n")
+	assert.NoError(t, err)
+	assert.True(t, doc.Comments.IsEmpty())
+	assert.True(t, doc.Nodes[0].Comments.IsEmpty())
+	assert.True(t, doc.Nodes[1].Comments.IsEmpty())
+}
+
+func TestCommentsRoundTrip(t *testing.T) {
+
+	src := `// header
+
+// first
+a 1 {
+    // child
+    b /* two */
+} // end of a
+
+/* third */
+c
+// footer
+`
+
+	doc, err := ParseString(src)
+	assert.NoError(t, err)
+	s, err := doc.WriteString()
+	assert.NoError(t, err)
+	assert.Equal(t, src, s)
+}
+
+func TestWritesCommentsWithoutDelimiters(t *testing.T) {
+
+	n := NewNode("port")
+	n.AddArg(8080)
+	n.Comments.Before = []string{"Port to listen on.\nDefaults to 8080."}
+	n.Comments.After = []string{"/* http */"}
+
+	doc := NewDocument()
+	doc.Comments.Before = []string{"Generated by a tool"}
+	doc.AddChild(n)
+
+	s, err := doc.WriteString()
+	assert.NoError(t, err)
+	assert.Equal(t, `// Generated by a tool
+
+// Port to listen on.
+// Defaults to 8080.
+port 8080 /* http */
+`, s)
+}
@@ -0,0 +1,141 @@
+package kdl
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+)
+
+// Decoder reads top-level nodes from a KDL document one at a time.
+//
+// Unlike ParseReader, a Decoder does not keep the whole document in memory:
+// a node is returned as soon as it has been read completely.
+type Decoder struct {
+	r                     reader
+	err                   error
+	disallowUnknownFields bool
+	registry              *Registry
+	fields                *fieldCache
+}
+
+// NewDecoder creates a new Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: wrapReader(bufio.NewReader(r))}
+}
+
+// DisallowUnknownFields makes the Decoder return an *UnknownFieldsError
+// when a node or a property does not match any field of the target struct.
+// The error lists all such fields, together with their positions in the document.
+func (d *Decoder) DisallowUnknownFields() {
+	d.disallowUnknownFields = true
+}
+
+// Next reads the next top-level node of the document.
+//
+// Returns io.EOF when there are no more nodes.
+// Once an error has been returned, every subsequent call returns the same error.
+func (d *Decoder) Next() (Node, error) {
+	return d.next(nil)
+}
+
+// next reads the next top-level node of the document,
+// recording its positions in span, if it is not nil.
+func (d *Decoder) next(span *nodeSpan) (Node, error) {
+
+	if d.err != nil {
+		return NewNode(""), d.err
+	}
+
+	node, done, err := readNextNode(&d.r, span)
+	if err != nil {
+		d.err = addErrPosInfo(err, &d.r)
+		return node, d.err
+	}
+
+	if done {
+		d.err = io.EOF
+		return node, d.err
+	}
+
+	return node, nil
+}
+
+// UseVersion makes the Decoder read the document as the provided version of KDL.
+// By default, the version is detected like by ParseOptions.
+// It should be called before the first node is read.
+func (d *Decoder) UseVersion(v Version) {
+	d.r.version = v
+}
+
+// UseRegistry makes the Decoder store nodes in interface values
+// using the types registered in r.
+func (d *Decoder) UseRegistry(r *Registry) {
+	d.registry = r
+}
+
+// UseNameMapper makes the Decoder match nodes and properties with struct fields named by m,
+// instead of by the lowercased names of the fields.
+// The names are cached for as long as the Decoder is used.
+func (d *Decoder) UseNameMapper(m NameMapper) {
+	d.fields = newFieldCache(m)
+}
+
+func (d *Decoder) newContext() *unmarshalContext {
+	return &unmarshalContext{
+		disallowUnknownFields: d.disallowUnknownFields,
+		registry:              d.registry,
+		fields:                d.fields,
+	}
+}
+
+// Decode reads the next top-level node of the document
+// and stores it in the value pointed to by v.
+//
+// Scalars are read from the only argument of the node,
+// while structs and maps are filled the same way Unmarshal fills nested nodes.
+// Returns io.EOF when there are no more nodes.
+func (d *Decoder) Decode(v any) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errUnmarshalTarget
+	}
+
+	var span nodeSpan
+	n, err := d.next(&span)
+	if err != nil {
+		return err
+	}
+
+	c := d.newContext()
+	return c.finish(nodeToValue(c, &n, &span, rv.Elem()))
+}
+
+// DecodeDocument reads all remaining top-level nodes of the document
+// and stores them in the value pointed to by v, the same way Unmarshal does.
+func (d *Decoder) DecodeDocument(v any) error {
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return errUnmarshalTarget
+	}
+
+	nodes := make([]Node, 0, 8)
+	spans := make([]nodeSpan, 0, 8)
+	for {
+		var span nodeSpan
+		n, err := d.next(&span)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, n)
+		spans = append(spans, span)
+	}
+
+	// An Unmarshaler reads a single node, so the document is always stored field by field
+	c := d.newContext()
+	return c.finish(childrenToFields(c, nodes, spans, rv.Elem()))
+}
@@ -0,0 +1,174 @@
+package kdl
+
+import (
+	"io"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoderYieldsNodes(t *testing.T) {
+
+	d := NewDecoder(strings.NewReader(`
+		first 1
+		/-skipped 2
+		second {
+			child
+		}
+		third; fourth
+	`))
+
+	names := make([]Identifier, 0, 4)
+	for {
+		n, err := d.Next()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		names = append(names, n.Name)
+	}
+
+	assert.Equal(t, []Identifier{"first", "second", "third", "fourth"}, names)
+
+	_, err := d.Next()
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestDecoderDecodesNodes(t *testing.T) {
+
+	type entry struct {
+		User   string `kdl:",argument"`
+		Action string
+	}
+
+	d := NewDecoder(strings.NewReader(`
+		audit "alice" action="login"
+		audit "bob" action="logout"
+	`))
+
+	var e entry
+	assert.NoError(t, d.Decode(&e))
+	assert.Equal(t, entry{User: "alice", Action: "login"}, e)
+	assert.NoError(t, d.Decode(&e))
+	assert.Equal(t, entry{User: "bob", Action: "logout"}, e)
+	assert.ErrorIs(t, d.Decode(&e), io.EOF)
+}
+
+func TestDecoderReportsPosition(t *testing.T) {
+
+	d := NewDecoder(strings.NewReader("good 1\nbad 1x\n"))
+
+	_, err := d.Next()
+	assert.NoError(t, err)
+
+	_, err = d.Next()
+	var posErr *ErrWithPosition
+	if assert.ErrorAs(t, err, &posErr) {
+		assert.Equal(t, 2, posErr.Line)
+	}
+	assert.ErrorIs(t, err, ErrInvalidSyntax)
+}
+
+func TestDecoderDisallowsUnknownFields(t *testing.T) {
+
+	type server struct {
+		Host string `kdl:",argument"`
+		Port int
+	}
+
+	var cfg struct {
+		Server server
+		Name   string
+	}
+
+	d := NewDecoder(strings.NewReader(`name "prod"
+server "example.com" prot=8080 {
+	tls
+}
+nmae "typo"
+`))
+	d.DisallowUnknownFields()
+	err := d.DecodeDocument(&cfg)
+
+	var unknownErr *UnknownFieldsError
+	if assert.ErrorAs(t, err, &unknownErr) && assert.Len(t, unknownErr.Errs, 3) {
+
+		positions := make([][2]int, 0, 3)
+		for _, e := range unknownErr.Errs {
+			var posErr *ErrWithPosition
+			if assert.ErrorAs(t, e, &posErr) {
+				positions = append(positions, [2]int{posErr.Line, posErr.Column})
+			}
+		}
+		assert.Equal(t, [][2]int{{2, 21}, {3, 1}, {5, 0}}, positions)
+
+		assert.Contains(t, err.Error(), `property "prot" of node "server"`)
+		assert.Contains(t, err.Error(), `node "server.tls"`)
+		assert.Contains(t, err.Error(), `node "nmae"`)
+	}
+	assert.ErrorIs(t, err, ErrUnknownField)
+
+	// Known fields are still decoded
+	assert.Equal(t, "prod", cfg.Name)
+	assert.Equal(t, "example.com", cfg.Server.Host)
+}
+
+func TestDecoderReportsDecodingPosition(t *testing.T) {
+
+	var cfg struct {
+		Port uint8
+	}
+
+	err := Unmarshal([]byte("\n\nport 300"), &cfg)
+	var posErr *ErrWithPosition
+	if assert.ErrorAs(t, err, &posErr) {
+		assert.Equal(t, 3, posErr.Line)
+	}
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+}
+
+func TestDecoderReadsSelectedVersion(t *testing.T) {
+
+	d := NewDecoder(strings.NewReader("node #false\n"))
+	d.UseVersion(V2)
+
+	n, err := d.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, false, n.Args[0].BoolValue())
+}
+
+func TestDecodesNaN(t *testing.T) {
+
+	var cfg struct {
+		Ratio float64
+		Exact *big.Float
+	}
+
+	d := NewDecoder(strings.NewReader("ratio #nan\n"))
+	d.UseVersion(V2)
+	assert.NoError(t, d.DecodeDocument(&cfg))
+	assert.True(t, math.IsNaN(cfg.Ratio))
+
+	d = NewDecoder(strings.NewReader("exact #nan\n"))
+	d.UseVersion(V2)
+	assert.ErrorIs(t, d.DecodeDocument(&cfg), ErrCannotUnmarshal)
+}
+
+func TestDecodesInfinityWithFloatHint(t *testing.T) {
+
+	var cfg struct {
+		Ratio float64
+		Small float32
+	}
+
+	d := NewDecoder(strings.NewReader("ratio (f64)#inf\nsmall (f32)#-inf\n"))
+	d.UseVersion(V2)
+	assert.NoError(t, d.DecodeDocument(&cfg))
+	assert.True(t, math.IsInf(cfg.Ratio, 1))
+	assert.True(t, math.IsInf(float64(cfg.Small), -1))
+}
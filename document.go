@@ -2,7 +2,8 @@ package kdl
 
 // Document is a top-level unit of the KDL format.
 type Document struct {
-	Nodes []Node
+	Nodes    []Node
+	Comments Comments // Comments at the start and at the end of the document.
 }
 
 // NewDocument creates a new Document.
@@ -0,0 +1,78 @@
+package kdl
+
+import (
+	"io"
+	"reflect"
+)
+
+// Encoder writes Go values to an io.Writer as KDL documents.
+type Encoder struct {
+	w           io.Writer
+	typeHints   bool
+	registry    *Registry
+	fields      *fieldCache
+	compareKeys func(a, b string) int
+	output      WriteOptions
+}
+
+// NewEncoder creates a new Encoder writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w}
+}
+
+// EmitTypeHints makes the Encoder annotate numbers with the reserved type hint
+// matching their Go type, such as (u8) for uint8 or (f64) for float64.
+// Numbers that already have a hint, eg. from the "hint=" tag option, are left as they are.
+func (e *Encoder) EmitTypeHints() {
+	e.typeHints = true
+}
+
+// UseRegistry makes the Encoder annotate nodes holding values of registered types
+// with their registered type hints, and name elements of slices with their registered names.
+func (e *Encoder) UseRegistry(r *Registry) {
+	e.registry = r
+}
+
+// UseNameMapper makes the Encoder name nodes and properties with m,
+// instead of lowercasing the names of struct fields.
+// The names are cached by the Encoder, so reuse it to avoid mapping them again.
+func (e *Encoder) UseNameMapper(m NameMapper) {
+	e.fields = newFieldCache(m)
+}
+
+// SortMapKeys makes the Encoder write entries of maps in the order defined by cmp,
+// which compares the names of their nodes and returns a negative number if a goes first,
+// a positive number if b goes first, or zero if their order does not matter.
+//
+// By default, the entries are sorted by their names in ascending lexical order.
+func (e *Encoder) SortMapKeys(cmp func(a, b string) int) {
+	e.compareKeys = cmp
+}
+
+// UseWriteOptions makes the Encoder write documents as configured by o,
+// eg. in KDL 2.0.0 instead of KDL 1.0.0.
+func (e *Encoder) UseWriteOptions(o WriteOptions) {
+	e.output = o
+}
+
+func (e *Encoder) newContext() *marshalContext {
+	return &marshalContext{
+		chain:       make([]reflect.Value, 0, 8),
+		typeHints:   e.typeHints,
+		registry:    e.registry,
+		fields:      e.fields,
+		compareKeys: e.compareKeys,
+	}
+}
+
+// Encode writes the KDL encoding of v to the underlying writer.
+// See Marshal for details.
+func (e *Encoder) Encode(v any) error {
+
+	doc, err := marshalDocument(e.newContext(), v)
+	if err != nil {
+		return err
+	}
+
+	return e.output.Write(e.w, &doc)
+}
@@ -0,0 +1,97 @@
+package kdl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncoderEmitsTypeHints(t *testing.T) {
+
+	type limits struct {
+		Retries uint8
+		Timeout float64 `kdl:",argument"`
+		Weights []int32 `kdl:",arguments"`
+		Label   string
+		Scale   float32 `kdl:",hint=ratio"`
+	}
+
+	in := struct {
+		Limits limits
+		Port   uint16
+		Offset *int
+	}{
+		Limits: limits{Retries: 3, Timeout: 1.5, Weights: []int32{1, -2}, Label: "x", Scale: 0.5},
+		Port:   8080,
+	}
+
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.EmitTypeHints()
+	assert.NoError(t, e.Encode(in))
+	assert.Equal(t, `limits (f64)1.5 (i32)1 (i32)-2 label="x" retries=(u8)3 scale=(ratio)0.5
+port (u16)8080
+offset null
+`, b.String())
+
+	var out = in
+	out.Limits = limits{}
+	assert.NoError(t, Unmarshal([]byte(b.String()), &out))
+	assert.Equal(t, in, out)
+}
+
+func TestMarshalsHintTagOption(t *testing.T) {
+
+	type listener struct {
+		Port int `kdl:",argument"`
+	}
+
+	in := struct {
+		Listener listener `kdl:",hint=http"`
+		Size     int64    `kdl:",hint=bytes"`
+		Ratio    float64  `kdl:",string,hint=percent"`
+	}{Listener: listener{80}, Size: 512, Ratio: 0.5}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `(http)listener 80
+size (bytes)512
+ratio (percent)"0.5"
+`, string(data))
+}
+
+func TestEncoderSortsMapKeys(t *testing.T) {
+
+	in := map[string]int{"delta": 4, "alpha": 1, "charlie": 3, "bravo": 2}
+
+	for i := 0; i < 10; i++ {
+		data, err := Marshal(in)
+		assert.NoError(t, err)
+		assert.Equal(t, "alpha 1\nbravo 2\ncharlie 3\ndelta 4\n", string(data))
+	}
+
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.SortMapKeys(func(a, b string) int { return len(a) - len(b) })
+	assert.NoError(t, e.Encode(map[string]bool{"ccc": true, "a": true, "bb": true}))
+	assert.Equal(t, "a true\nbb true\nccc true\n", b.String())
+}
+
+func TestEncoderWritesSelectedVersion(t *testing.T) {
+
+	in := struct {
+		Enabled bool
+		Path    string
+		Parent  *string
+	}{Enabled: true, Path: `C:\kdl`}
+
+	var b strings.Builder
+	e := NewEncoder(&b)
+	e.UseWriteOptions(WriteOptions{Version: V2})
+	assert.NoError(t, e.Encode(in))
+	assert.Equal(t, `enabled #true
+path #"C:\kdl"#
+parent #null
+`, b.String())
+}
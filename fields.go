@@ -0,0 +1,310 @@
+package kdl
+
+import (
+	"bufio"
+	"io"
+	"math/big"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+type purpose int
+
+const (
+	purposeArgument purpose = iota
+	purposeProperty
+	purposeArguments
+	purposeChild
+	purposeChildren
+	purposeName     // The name of the node itself.
+	purposeTypeHint // The type hint of the node itself.
+	purposeProps    // Properties not claimed by any other field.
+)
+
+// isNamed checks if fields of this purpose are matched with nodes or properties by their names.
+func (p purpose) isNamed() bool {
+	return p == purposeProperty || p == purposeChild || p == purposeChildren
+}
+
+// fieldInfo describes how a single struct field maps onto KDL.
+type fieldInfo struct {
+	index     []int
+	name      string
+	purpose   purpose
+	omitEmpty bool // Skip the field if it holds an empty value.
+	inline    bool // Merge fields of the embedded struct into the parent.
+	asString  bool // Write numbers and booleans as strings.
+	required  bool // Fail decoding if the field is not present.
+
+	hint         TypeHint // Type hint written with the field, if present.
+	defaultValue *Value   // Value assigned to the field before decoding, if any.
+}
+
+// parseField reads the `kdl` tag of a struct field.
+// Returns false if the field should not be marshaled nor unmarshaled.
+//
+// Unless specified otherwise, fields holding structs, maps, slices or arrays
+// become child nodes and all other fields become properties.
+// Their names are derived from the names of the fields with the NameMapper.
+func parseField(sf reflect.StructField, m NameMapper) (fieldInfo, bool) {
+
+	info := fieldInfo{
+		index:   sf.Index,
+		name:    mapFieldName(m, sf.Name),
+		purpose: purposeProperty,
+	}
+
+	if isCompositeType(sf.Type) {
+		info.purpose = purposeChild
+	}
+
+	tag, ok := sf.Tag.Lookup("kdl")
+	if !ok {
+		return info, sf.IsExported()
+	}
+
+	opts := strings.Split(tag, ",")
+	if opts[0] == "-" {
+		return info, false
+	}
+
+	if opts[0] != "" {
+		info.name = opts[0]
+	}
+
+	for _, opt := range opts[1:] {
+		switch opt {
+		case "argument":
+			info.purpose = purposeArgument
+		case "arguments", "args":
+			info.purpose = purposeArguments
+		case "property":
+			info.purpose = purposeProperty
+		case "child":
+			info.purpose = purposeChild
+		case "children":
+			info.purpose = purposeChildren
+		case "name":
+			info.purpose = purposeName
+		case "typehint":
+			info.purpose = purposeTypeHint
+		case "props":
+			info.purpose = purposeProps
+		case "omitempty":
+			info.omitEmpty = true
+		case "inline":
+			info.inline = indirectType(sf.Type).Kind() == reflect.Struct
+		case "string":
+			info.asString = isStringableType(sf.Type)
+		case "required":
+			info.required = true
+		default:
+			if value, ok := strings.CutPrefix(opt, "default="); ok {
+				def := parseDefaultValue(value)
+				info.defaultValue = &def
+			} else if value, ok := strings.CutPrefix(opt, "hint="); ok {
+				info.hint = Hint(value)
+			}
+		}
+	}
+
+	// Exported fields of unexported embedded structs are still reachable
+	return info, sf.IsExported() || (info.inline && sf.Anonymous)
+}
+
+// parseDefaultValue reads the value of a "default=" tag option.
+// It is parsed as a KDL value, or used as a plain string if it is not a valid one.
+func parseDefaultValue(s string) Value {
+	r := wrapReader(bufio.NewReader(strings.NewReader(s)))
+	v, err := readValue(&r)
+	if err == nil {
+		if _, err = r.peekByte(); err == io.EOF {
+			return v
+		}
+	}
+	return NewStringValue(s, NoHint())
+}
+
+// structFields returns mapping information for every eligible field of a struct type,
+// including fields of inlined structs.
+//
+// If more than one field maps to the same name and purpose,
+// the least nested one is used.
+func structFields(t reflect.Type, m NameMapper) []fieldInfo {
+
+	fields := appendStructFields(make([]fieldInfo, 0, t.NumField()), t, nil, m)
+
+	type key struct {
+		name    string
+		purpose purpose
+	}
+
+	best := make(map[key]int, len(fields))
+	for i, f := range fields {
+		k := key{f.name, f.purpose}
+		if !f.purpose.isNamed() {
+			continue
+		}
+		if j, ok := best[k]; !ok || len(f.index) < len(fields[j].index) {
+			best[k] = i
+		}
+	}
+
+	res := fields[:0]
+	for i, f := range fields {
+		if f.purpose.isNamed() {
+			if best[key{f.name, f.purpose}] != i {
+				continue
+			}
+		}
+		res = append(res, f)
+	}
+	return res
+}
+
+// fieldCache stores mapping information of struct types,
+// so that their fields and tags are only inspected once per type.
+// It is safe for concurrent use.
+type fieldCache struct {
+	mapper NameMapper
+	types  sync.Map // map[reflect.Type][]fieldInfo
+}
+
+// defaultFieldCache is used by Encoders and Decoders without a NameMapper.
+var defaultFieldCache = &fieldCache{}
+
+// newFieldCache returns the fieldCache for an Encoder or a Decoder using the NameMapper.
+// Caches of mappers other than the default one belong to a single Encoder or Decoder,
+// and are dropped together with it.
+func newFieldCache(m NameMapper) *fieldCache {
+	if m == nil {
+		return defaultFieldCache
+	}
+	return &fieldCache{mapper: m}
+}
+
+// structFields returns the cached mapping information for every eligible field of a struct type.
+// The returned slice is shared and must not be modified.
+func (fc *fieldCache) structFields(t reflect.Type) []fieldInfo {
+	if fc == nil {
+		fc = defaultFieldCache
+	}
+	if fields, ok := fc.types.Load(t); ok {
+		return fields.([]fieldInfo)
+	}
+	fields, _ := fc.types.LoadOrStore(t, structFields(t, fc.mapper))
+	return fields.([]fieldInfo)
+}
+
+func appendStructFields(fields []fieldInfo, t reflect.Type, index []int, m NameMapper) []fieldInfo {
+
+	for i := 0; i < t.NumField(); i++ {
+
+		sf := t.Field(i)
+		info, ok := parseField(sf, m)
+		if !ok {
+			continue
+		}
+
+		info.index = make([]int, len(index)+1)
+		copy(info.index, index)
+		info.index[len(index)] = i
+
+		if info.inline {
+			fields = appendStructFields(fields, indirectType(sf.Type), info.index, m)
+			continue
+		}
+
+		fields = append(fields, info)
+	}
+
+	return fields
+}
+
+// indirectType returns the type pointers of this type eventually point to.
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
+
+// isStringableType checks if values of this type can be written as strings
+// with the "string" tag option, ie. if they are numbers or booleans.
+func isStringableType(t reflect.Type) bool {
+	t = indirectType(t)
+	if isScalarType(t) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// isEmptyValue checks if the value should be skipped by the "omitempty" tag option.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+var (
+	typeValue    = reflect.TypeOf(Value{})
+	typeTypeHint = reflect.TypeOf(TypeHint{})
+	typeNodes    = reflect.TypeOf([]Node(nil))
+	typeBigInt   = reflect.TypeOf((*big.Int)(nil))
+	typeBigFloat = reflect.TypeOf((*big.Float)(nil))
+)
+
+// isScalarType checks if values of this type can only be stored as a single Value.
+func isScalarType(t reflect.Type) bool {
+	if t == typeValue || t == typeBigInt || t == typeBigFloat || isReservedType(t) {
+		return true
+	}
+	p := reflect.PointerTo(t)
+	return p.Implements(typeValueMarshaler) || p.Implements(typeValueUnmarshaler) ||
+		p.Implements(typeTextMarshaler) || p.Implements(typeTextUnmarshaler)
+}
+
+// isCompositeType checks if values of this type need a whole node to be represented.
+func isCompositeType(t reflect.Type) bool {
+	for {
+		if isScalarType(t) {
+			return false
+		}
+		switch t.Kind() {
+		case reflect.Pointer:
+			t = t.Elem()
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+			return true
+		case reflect.Interface:
+			// Interfaces with methods hold nodes decoded with a Registry
+			return t.NumMethod() > 0
+		default:
+			return false
+		}
+	}
+}
+
+// isSequenceType checks if the type is a slice or an array that is not a scalar itself.
+func isSequenceType(t reflect.Type) bool {
+	k := t.Kind()
+	return (k == reflect.Slice || k == reflect.Array) && !isScalarType(t)
+}
+
+// isRepeatedType checks if values of this type are represented
+// by repeating a node once for every element.
+// Slices of Nodes are never repeated, as they hold raw child nodes.
+func isRepeatedType(t reflect.Type) bool {
+	return isSequenceType(t) && isCompositeType(t.Elem()) && t != typeNodes
+}
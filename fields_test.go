@@ -0,0 +1,82 @@
+package kdl
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachesStructFields(t *testing.T) {
+
+	type point struct {
+		X, Y       int
+		PointLabel string `kdl:",argument"`
+	}
+
+	typ := reflect.TypeOf(point{})
+	fc := newFieldCache(nil)
+	assert.Same(t, defaultFieldCache, fc)
+
+	var wg sync.WaitGroup
+	results := make([][]fieldInfo, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = fc.structFields(typ)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, fields := range results {
+		assert.Same(t, &results[0][0], &fields[0])
+	}
+
+	kebab := newFieldCache(KebabCase)
+	names := make([]string, 0, 3)
+	for _, f := range kebab.structFields(typ) {
+		names = append(names, f.name)
+	}
+	assert.Equal(t, []string{"x", "y", "point-label"}, names)
+	assert.Equal(t, "pointlabel", fc.structFields(typ)[2].name)
+	assert.Same(t, &kebab.structFields(typ)[0], &kebab.structFields(typ)[0])
+}
+
+func TestCopiesDefaultValues(t *testing.T) {
+
+	type config struct {
+		Limit Value `kdl:",default=100"`
+	}
+
+	var a, b config
+	assert.NoError(t, Unmarshal([]byte(""), &a))
+	assert.NoError(t, Unmarshal([]byte(""), &b))
+	assert.NotSame(t, a.Limit.IntegerValue(), b.Limit.IntegerValue())
+
+	a.Limit.IntegerValue().SetInt64(1)
+	var c config
+	assert.NoError(t, Unmarshal([]byte(""), &c))
+	assert.Equal(t, int64(100), c.Limit.IntegerValue().Int64())
+}
+
+func BenchmarkMarshalStructSlice(b *testing.B) {
+
+	type item struct {
+		ID    int    `kdl:",argument"`
+		Name  string `kdl:",omitempty"`
+		Price float64
+		Tags  []string
+	}
+
+	items := make([]item, 1000)
+	for i := range items {
+		items[i] = item{ID: i, Name: "item", Price: 1.5, Tags: []string{"a", "b"}}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = Marshal(items)
+	}
+}
@@ -0,0 +1,39 @@
+// Package example holds structs with generated MarshalKDL and UnmarshalKDL methods,
+// which are compared with kdl.Marshal and kdl.Unmarshal in tests.
+package example
+
+import (
+	"github.com/frixuu/kdlgo"
+)
+
+//go:generate go run github.com/frixuu/kdlgo/internal/tools/generate_marshalers -type Server,Listener,TLS
+
+type Port uint16
+
+type Server struct {
+	Name      string     `kdl:",argument"`
+	Aliases   []string   `kdl:",arguments"`
+	Workers   int        `kdl:",default=4"`
+	Ratio     float32    `kdl:",omitempty"`
+	Debug     bool       `kdl:",string"`
+	Region    string     `kdl:",required,hint=region"`
+	Extra     kdl.Value  `kdl:"extra"`
+	Listeners []Listener `kdl:"listener"`
+	TLS       *TLS
+	Admin     Listener
+	Tags      []string
+	Timeout   int64 `kdl:",child"`
+	internal  int
+	Skipped   string `kdl:"-"`
+}
+
+type Listener struct {
+	Port    Port   `kdl:",argument,required"`
+	Address string `kdl:",omitempty"`
+	Backlog uint8  `kdl:",hint=u8"`
+}
+
+type TLS struct {
+	Cert string `kdl:",required"`
+	Key  string
+}
@@ -0,0 +1,126 @@
+package example
+
+import (
+	"testing"
+
+	"github.com/frixuu/kdlgo"
+	"github.com/stretchr/testify/assert"
+)
+
+// plainServer has the same fields as Server, but none of its generated methods.
+type plainServer Server
+
+func TestGeneratedMarshalMatchesReflection(t *testing.T) {
+
+	s := Server{
+		Name:    "main",
+		Aliases: []string{"primary", "default"},
+		Workers: 8,
+		Ratio:   0.25,
+		Debug:   true,
+		Region:  "eu",
+		Extra:   kdl.NewStringValue("extra", kdl.NoHint()),
+		Listeners: []Listener{
+			{Port: 80, Address: "0.0.0.0"},
+			{Port: 443, Backlog: 16},
+		},
+		TLS:     &TLS{Cert: "cert.pem", Key: "key.pem"},
+		Admin:   Listener{Port: 9000},
+		Tags:    []string{"a", "b"},
+		Timeout: 30,
+	}
+
+	generated, err := kdl.Marshal(struct{ Server Server }{s})
+	assert.NoError(t, err)
+
+	reflected, err := kdl.Marshal(struct{ Server plainServer }{plainServer(s)})
+	assert.NoError(t, err)
+
+	assert.Equal(t, string(reflected), string(generated))
+
+	s.TLS = nil
+	s.Ratio = 0
+	generated, err = kdl.Marshal(struct{ Server Server }{s})
+	assert.NoError(t, err)
+	reflected, err = kdl.Marshal(struct{ Server plainServer }{plainServer(s)})
+	assert.NoError(t, err)
+	assert.Equal(t, string(reflected), string(generated))
+}
+
+func TestGeneratedUnmarshalMatchesReflection(t *testing.T) {
+
+	doc := []byte(`server "main" "primary" region=(region)"eu" debug="true" extra=5 {
+		listener 80 address="0.0.0.0"
+		listener 443 backlog=(u8)16
+		tls cert="cert.pem"
+		admin 9000
+		tags "a" "b"
+		timeout 30
+	}`)
+
+	var generated struct{ Server Server }
+	assert.NoError(t, kdl.Unmarshal(doc, &generated))
+
+	var reflected struct{ Server plainServer }
+	assert.NoError(t, kdl.Unmarshal(doc, &reflected))
+
+	assert.Equal(t, Server(reflected.Server), generated.Server)
+	assert.Equal(t, 4, generated.Server.Workers)
+	assert.Equal(t, Port(443), generated.Server.Listeners[1].Port)
+	assert.Equal(t, "cert.pem", generated.Server.TLS.Cert)
+}
+
+func TestGeneratedUnmarshalReportsErrors(t *testing.T) {
+
+	for _, doc := range []string{
+		`server "main"`,
+		`server "main" region="eu" { listener; }`,
+		`server "main" region="eu" { tls key="key.pem"; }`,
+	} {
+		var generated struct{ Server Server }
+		assert.ErrorIs(t, kdl.Unmarshal([]byte(doc), &generated), kdl.ErrMissingField, doc)
+
+		var reflected struct{ Server plainServer }
+		assert.ErrorIs(t, kdl.Unmarshal([]byte(doc), &reflected), kdl.ErrMissingField, doc)
+	}
+
+	for _, doc := range []string{
+		`server "main" region="eu" { listener 70000; }`,
+		`server "main" region="eu" debug="maybe"`,
+		`server "main" region="eu" { timeout "soon"; }`,
+		`server "main" region="eu" { listener 80 backlog=(u8)300; }`,
+	} {
+		var generated struct{ Server Server }
+		assert.ErrorIs(t, kdl.Unmarshal([]byte(doc), &generated), kdl.ErrCannotUnmarshal, doc)
+
+		var reflected struct{ Server plainServer }
+		assert.ErrorIs(t, kdl.Unmarshal([]byte(doc), &reflected), kdl.ErrCannotUnmarshal, doc)
+	}
+}
+
+func TestTopLevelGeneratedTypeIsMappedFieldByField(t *testing.T) {
+
+	s := Server{
+		Name:      "main",
+		Workers:   8,
+		Region:    "eu",
+		Extra:     kdl.NewStringValue("extra", kdl.NoHint()),
+		Listeners: []Listener{{Port: 80, Address: "0.0.0.0"}},
+		Admin:     Listener{Port: 9000},
+	}
+
+	generated, err := kdl.Marshal(s)
+	assert.NoError(t, err)
+	reflected, err := kdl.Marshal(plainServer(s))
+	assert.NoError(t, err)
+	assert.Equal(t, string(reflected), string(generated))
+	assert.Contains(t, string(generated), "\nregion (region)\"eu\"\n")
+
+	var out Server
+	assert.NoError(t, kdl.Unmarshal(generated, &out))
+	var expected plainServer
+	assert.NoError(t, kdl.Unmarshal(reflected, &expected))
+	assert.Equal(t, Server(expected), out)
+	assert.Equal(t, "eu", out.Region)
+	assert.Equal(t, Port(80), out.Listeners[0].Port)
+}
@@ -0,0 +1,414 @@
+// Code generated by generate_marshalers. DO NOT EDIT.
+
+package example
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+
+	kdl "github.com/frixuu/kdlgo"
+)
+
+// MarshalKDL implements kdl.Marshaler.
+func (v Server) MarshalKDL() (kdl.Node, error) {
+
+	n := kdl.NewNode("")
+
+	// Name
+	{
+		val := kdl.NewStringValue(string(v.Name), kdl.NoHint())
+		n.AddArgValue(val)
+	}
+
+	// Aliases
+	{
+		for _, e := range v.Aliases {
+			val := kdl.NewStringValue(string(e), kdl.NoHint())
+			n.AddArgValue(val)
+		}
+	}
+
+	// Workers
+	{
+		val := kdl.NewIntegerValue(big.NewInt(int64(v.Workers)), kdl.NoHint())
+		n.SetPropValue("workers", val)
+	}
+
+	// Ratio
+	if v.Ratio != 0 {
+		if math.IsNaN(float64(v.Ratio)) {
+			return n, errors.New("cannot marshal NaN")
+		}
+		val := kdl.NewFloatValue(big.NewFloat(float64(v.Ratio)), kdl.NoHint())
+		n.SetPropValue("ratio", val)
+	}
+
+	// Debug
+	{
+		val := kdl.NewStringValue(strconv.FormatBool(bool(v.Debug)), kdl.NoHint())
+		n.SetPropValue("debug", val)
+	}
+
+	// Region
+	{
+		val := kdl.NewStringValue(string(v.Region), kdl.Hint("region"))
+		n.SetPropValue("region", val)
+	}
+
+	// Extra
+	{
+		val := v.Extra
+		n.SetPropValue("extra", val)
+	}
+
+	// Listeners
+	{
+		for _, e := range v.Listeners {
+			c, err := e.MarshalKDL()
+			if err != nil {
+				return n, err
+			}
+			if len(c.Name) == 0 {
+				c.Name = "listener"
+			}
+			n.AddChild(c)
+		}
+	}
+
+	// TLS
+	{
+		c := kdl.NewNode("tls")
+		if v.TLS == nil {
+			c.AddArgValue(kdl.NewNullValue(kdl.NoHint()))
+		} else {
+			var err error
+			if c, err = v.TLS.MarshalKDL(); err != nil {
+				return n, err
+			}
+		}
+		if len(c.Name) == 0 {
+			c.Name = "tls"
+		}
+		n.AddChild(c)
+	}
+
+	// Admin
+	{
+		c, err := v.Admin.MarshalKDL()
+		if err != nil {
+			return n, err
+		}
+		if len(c.Name) == 0 {
+			c.Name = "admin"
+		}
+		n.AddChild(c)
+	}
+
+	// Tags
+	{
+		c := kdl.NewNode("tags")
+		for _, e := range v.Tags {
+			val := kdl.NewStringValue(string(e), kdl.NoHint())
+			c.AddArgValue(val)
+		}
+		n.AddChild(c)
+	}
+
+	// Timeout
+	{
+		val := kdl.NewIntegerValue(big.NewInt(int64(v.Timeout)), kdl.NoHint())
+		c := kdl.NewNode("timeout")
+		c.AddArgValue(val)
+		n.AddChild(c)
+	}
+
+	return n, nil
+}
+
+// UnmarshalKDL implements kdl.Unmarshaler.
+func (v *Server) UnmarshalKDL(n *kdl.Node) error {
+
+	v.Workers = int(4)
+
+	// Name
+	if len(n.Args) > 0 {
+		x, err := kdl.Arg[string](n, 0)
+		if err != nil {
+			return err
+		}
+		v.Name = string(x)
+	}
+
+	// Aliases
+	if len(n.Args) > 1 {
+		v.Aliases = make([]string, 0, len(n.Args)-1)
+		for i := 1; i < len(n.Args); i++ {
+			var e string
+			x, err := kdl.Arg[string](n, i)
+			if err != nil {
+				return err
+			}
+			e = string(x)
+			v.Aliases = append(v.Aliases, e)
+		}
+	}
+
+	// Workers
+	if n.HasProp("workers") {
+		x, err := kdl.Prop[int](n, "workers")
+		if err != nil {
+			return err
+		}
+		v.Workers = int(x)
+	}
+
+	// Ratio
+	if n.HasProp("ratio") {
+		x, err := kdl.Prop[float32](n, "ratio")
+		if err != nil {
+			return err
+		}
+		v.Ratio = float32(x)
+	}
+
+	// Debug
+	if n.HasProp("debug") {
+		if val := n.Props["debug"]; val.Type == kdl.TypeString {
+			x, err := strconv.ParseBool(val.StringValue())
+			if err != nil {
+				return fmt.Errorf("%w string (expected a number or a boolean): %w", kdl.ErrCannotUnmarshal, err)
+			}
+			v.Debug = bool(x)
+		} else {
+			x, err := kdl.Prop[bool](n, "debug")
+			if err != nil {
+				return err
+			}
+			v.Debug = bool(x)
+		}
+	}
+
+	// Region
+	if n.HasProp("region") {
+		x, err := kdl.Prop[string](n, "region")
+		if err != nil {
+			return err
+		}
+		v.Region = string(x)
+	} else {
+		return fmt.Errorf("%w: property %q of node %q", kdl.ErrMissingField, "region", n.Name)
+	}
+
+	// Extra
+	if n.HasProp("extra") {
+		x, err := kdl.Prop[kdl.Value](n, "extra")
+		if err != nil {
+			return err
+		}
+		v.Extra = kdl.Value(x)
+	}
+
+	// Listeners
+	{
+		var elems []Listener
+		for i := range n.Children {
+			c := &n.Children[i]
+			if c.Name != "listener" {
+				continue
+			}
+			var e Listener
+			if err := e.UnmarshalKDL(c); err != nil {
+				return fmt.Errorf("node %q: %w", c.Name, err)
+			}
+			elems = append(elems, e)
+		}
+		if len(elems) > 0 {
+			v.Listeners = elems
+		}
+	}
+
+	// TLS
+	for i := range n.Children {
+		c := &n.Children[i]
+		if c.Name != "tls" {
+			continue
+		}
+		if len(c.Args) == 1 && len(c.Props) == 0 && len(c.Children) == 0 && c.Args[0].Type == kdl.TypeNull {
+			v.TLS = nil
+			continue
+		}
+		if v.TLS == nil {
+			v.TLS = new(TLS)
+		}
+		if err := v.TLS.UnmarshalKDL(c); err != nil {
+			return fmt.Errorf("node %q: %w", c.Name, err)
+		}
+	}
+
+	// Admin
+	for i := range n.Children {
+		c := &n.Children[i]
+		if c.Name != "admin" {
+			continue
+		}
+		if err := v.Admin.UnmarshalKDL(c); err != nil {
+			return fmt.Errorf("node %q: %w", c.Name, err)
+		}
+	}
+
+	// Tags
+	for i := range n.Children {
+		c := &n.Children[i]
+		if c.Name != "tags" {
+			continue
+		}
+		v.Tags = make([]string, 0, len(c.Args))
+		for j := range c.Args {
+			var e string
+			if err := func() error {
+				x, err := kdl.Arg[string](c, j)
+				if err != nil {
+					return err
+				}
+				e = string(x)
+				return nil
+			}(); err != nil {
+				return fmt.Errorf("node %q: %w", c.Name, err)
+			}
+			v.Tags = append(v.Tags, e)
+		}
+	}
+
+	// Timeout
+	for i := range n.Children {
+		c := &n.Children[i]
+		if c.Name != "timeout" {
+			continue
+		}
+		if len(c.Args) != 1 {
+			return fmt.Errorf("node %q: %w node (expected exactly one argument)", c.Name, kdl.ErrCannotUnmarshal)
+		}
+		if err := func() error {
+			x, err := kdl.Arg[int64](c, 0)
+			if err != nil {
+				return err
+			}
+			v.Timeout = int64(x)
+			return nil
+		}(); err != nil {
+			return fmt.Errorf("node %q: %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// MarshalKDL implements kdl.Marshaler.
+func (v Listener) MarshalKDL() (kdl.Node, error) {
+
+	n := kdl.NewNode("")
+
+	// Port
+	{
+		val := kdl.NewIntegerValue(new(big.Int).SetUint64(uint64(v.Port)), kdl.NoHint())
+		n.AddArgValue(val)
+	}
+
+	// Address
+	if v.Address != "" {
+		val := kdl.NewStringValue(string(v.Address), kdl.NoHint())
+		n.SetPropValue("address", val)
+	}
+
+	// Backlog
+	{
+		val := kdl.NewIntegerValue(new(big.Int).SetUint64(uint64(v.Backlog)), kdl.Hint("u8"))
+		n.SetPropValue("backlog", val)
+	}
+
+	return n, nil
+}
+
+// UnmarshalKDL implements kdl.Unmarshaler.
+func (v *Listener) UnmarshalKDL(n *kdl.Node) error {
+
+	// Port
+	if len(n.Args) > 0 {
+		x, err := kdl.Arg[uint16](n, 0)
+		if err != nil {
+			return err
+		}
+		v.Port = Port(x)
+	} else {
+		return fmt.Errorf("%w: argument %d of node %q", kdl.ErrMissingField, 0, n.Name)
+	}
+
+	// Address
+	if n.HasProp("address") {
+		x, err := kdl.Prop[string](n, "address")
+		if err != nil {
+			return err
+		}
+		v.Address = string(x)
+	}
+
+	// Backlog
+	if n.HasProp("backlog") {
+		x, err := kdl.Prop[uint8](n, "backlog")
+		if err != nil {
+			return err
+		}
+		v.Backlog = uint8(x)
+	}
+
+	return nil
+}
+
+// MarshalKDL implements kdl.Marshaler.
+func (v TLS) MarshalKDL() (kdl.Node, error) {
+
+	n := kdl.NewNode("")
+
+	// Cert
+	{
+		val := kdl.NewStringValue(string(v.Cert), kdl.NoHint())
+		n.SetPropValue("cert", val)
+	}
+
+	// Key
+	{
+		val := kdl.NewStringValue(string(v.Key), kdl.NoHint())
+		n.SetPropValue("key", val)
+	}
+
+	return n, nil
+}
+
+// UnmarshalKDL implements kdl.Unmarshaler.
+func (v *TLS) UnmarshalKDL(n *kdl.Node) error {
+
+	// Cert
+	if n.HasProp("cert") {
+		x, err := kdl.Prop[string](n, "cert")
+		if err != nil {
+			return err
+		}
+		v.Cert = string(x)
+	} else {
+		return fmt.Errorf("%w: property %q of node %q", kdl.ErrMissingField, "cert", n.Name)
+	}
+
+	// Key
+	if n.HasProp("key") {
+		x, err := kdl.Prop[string](n, "key")
+		if err != nil {
+			return err
+		}
+		v.Key = string(x)
+	}
+
+	return nil
+}
@@ -0,0 +1,747 @@
+// Command generate_marshalers writes MarshalKDL and UnmarshalKDL methods for structs,
+// so that they can be converted to and from KDL nodes without looking up their fields at run time.
+// Values of fields are still converted with kdl.Arg and kdl.Prop.
+//
+// It is meant to be used in a go:generate directive of the package declaring the structs:
+//
+//	//go:generate go run github.com/frixuu/kdlgo/internal/tools/generate_marshalers -type Server,Listener
+//
+// The generated methods follow the same `kdl` struct tag rules as kdl.Marshal and kdl.Unmarshal,
+// with fields named after their lowercased names unless their tag says otherwise.
+// Fields can hold:
+//
+//   - strings, booleans, integers, floats, kdl.Value and named types based on them,
+//   - slices of those, as arguments or as the arguments of a child node,
+//   - other structs listed in -type, pointers to them and slices of them, as child nodes.
+//
+// Fields of other types, as well as the "children", "inline", "name", "typehint" and "props" tag options,
+// are reported as errors; such structs can still be handled by kdl.Marshal and kdl.Unmarshal.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/frixuu/kdlgo"
+)
+
+func panicOnError(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func main() {
+
+	typeNames := flag.String("type", "", "comma-separated list of struct names; must be set")
+	output := flag.String("output", "", "output file name; default <type>_kdl.go")
+	flag.Parse()
+
+	if len(*typeNames) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	names := strings.Split(*typeNames, ",")
+	if len(*output) == 0 {
+		*output = strings.ToLower(names[0]) + "_kdl.go"
+	}
+
+	pkg, err := loadPackage(".", *output)
+	panicOnError(err)
+
+	src, err := generate(pkg, names)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "generate_marshalers:", err)
+		os.Exit(1)
+	}
+
+	panicOnError(os.WriteFile(*output, src, 0o644))
+}
+
+// sourcePackage holds the declarations of the package the methods are generated for.
+type sourcePackage struct {
+	name  string
+	types map[string]ast.Expr // Underlying type expressions of all declared types.
+}
+
+func loadPackage(dir string, output string) (*sourcePackage, error) {
+
+	fset := token.NewFileSet()
+	filter := func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go") && fi.Name() != output
+	}
+
+	pkgs, err := parser.ParseDir(fset, dir, filter, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("expected exactly one package in %s, found %d", dir, len(pkgs))
+	}
+
+	pkg := &sourcePackage{types: make(map[string]ast.Expr)}
+	for name, p := range pkgs {
+		pkg.name = name
+		for _, f := range p.Files {
+			for _, decl := range f.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					ts := spec.(*ast.TypeSpec)
+					pkg.types[ts.Name.Name] = ts.Type
+				}
+			}
+		}
+	}
+
+	return pkg, nil
+}
+
+type typeKind int
+
+const (
+	kindBasic     typeKind = iota // A builtin type, or a named type based on one.
+	kindValue                     // kdl.Value
+	kindStruct                    // A struct with generated methods.
+	kindPtrStruct                 // A pointer to a struct with generated methods.
+	kindSlice                     // A slice of basic types or kdl.Values.
+	kindRepeated                  // A slice of structs with generated methods.
+)
+
+// goType describes a Go type supported by the generator.
+type goType struct {
+	expr  string // Type as written in the source.
+	kind  typeKind
+	basic string  // Builtin type the type is based on, for kindBasic and kindValue.
+	elem  *goType // Element type, for kindSlice and kindRepeated.
+}
+
+var basicTypes = map[string]bool{
+	"string": true, "bool": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true, "byte": true, "rune": true,
+}
+
+func isScalar(t *goType) bool {
+	return t.kind == kindBasic || t.kind == kindValue
+}
+
+const kdlImportPath = "github.com/frixuu/kdlgo"
+
+type generator struct {
+	pkg       *sourcePackage
+	generated map[string]bool
+	imports   map[string]bool
+	buf       bytes.Buffer
+}
+
+func (g *generator) resolve(expr ast.Expr) (*goType, error) {
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if basicTypes[e.Name] {
+			return &goType{expr: e.Name, kind: kindBasic, basic: e.Name}, nil
+		}
+		if g.generated[e.Name] {
+			return &goType{expr: e.Name, kind: kindStruct}, nil
+		}
+		if underlying, ok := g.pkg.types[e.Name]; ok {
+			if u, err := g.resolve(underlying); err == nil && u.kind == kindBasic {
+				return &goType{expr: e.Name, kind: kindBasic, basic: u.basic}, nil
+			}
+		}
+	case *ast.SelectorExpr:
+		if x, ok := e.X.(*ast.Ident); ok && x.Name == "kdl" && e.Sel.Name == "Value" {
+			return &goType{expr: "kdl.Value", kind: kindValue, basic: "kdl.Value"}, nil
+		}
+	case *ast.StarExpr:
+		if elem, err := g.resolve(e.X); err == nil && elem.kind == kindStruct {
+			return &goType{expr: "*" + elem.expr, kind: kindPtrStruct, elem: elem}, nil
+		}
+	case *ast.ArrayType:
+		if e.Len != nil {
+			break
+		}
+		elem, err := g.resolve(e.Elt)
+		if err != nil {
+			break
+		}
+		switch elem.kind {
+		case kindBasic, kindValue:
+			return &goType{expr: "[]" + elem.expr, kind: kindSlice, elem: elem}, nil
+		case kindStruct:
+			return &goType{expr: "[]" + elem.expr, kind: kindRepeated, elem: elem}, nil
+		}
+	}
+
+	return nil, errors.New("unsupported type")
+}
+
+type purpose int
+
+const (
+	purposeArgument purpose = iota
+	purposeProperty
+	purposeArguments
+	purposeChild
+)
+
+// field describes how a single struct field maps onto KDL,
+// mirroring the rules of the kdl package.
+type field struct {
+	goName    string
+	name      string
+	t         *goType
+	purpose   purpose
+	omitEmpty bool
+	asString  bool
+	required  bool
+	hint      string
+	hasHint   bool
+	def       *kdl.Value
+}
+
+func (g *generator) parseField(typeName string, name string, t *goType, tag string) (*field, bool, error) {
+
+	f := &field{goName: name, name: strings.ToLower(name), t: t, purpose: purposeProperty}
+	if t != nil && !isScalar(t) {
+		f.purpose = purposeChild
+	}
+
+	fail := func(msg string) (*field, bool, error) {
+		return nil, false, fmt.Errorf("%s.%s: %s", typeName, name, msg)
+	}
+
+	value, ok := reflect.StructTag(tag).Lookup("kdl")
+	if ok {
+
+		opts := strings.Split(value, ",")
+		if opts[0] == "-" {
+			return nil, false, nil
+		}
+		if len(opts[0]) > 0 {
+			f.name = opts[0]
+		}
+
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "argument":
+				f.purpose = purposeArgument
+			case "arguments", "args":
+				f.purpose = purposeArguments
+			case "property":
+				f.purpose = purposeProperty
+			case "child":
+				f.purpose = purposeChild
+			case "omitempty":
+				f.omitEmpty = true
+			case "string":
+				f.asString = t != nil && t.kind == kindBasic && t.basic != "string"
+			case "required":
+				f.required = true
+			case "children", "inline", "name", "typehint", "props":
+				return fail(fmt.Sprintf("the %q tag option is not supported", opt))
+			default:
+				if v, ok := strings.CutPrefix(opt, "default="); ok {
+					def := parseDefaultValue(v)
+					f.def = &def
+				} else if v, ok := strings.CutPrefix(opt, "hint="); ok {
+					f.hint, f.hasHint = v, true
+				}
+			}
+		}
+	}
+
+	if t == nil {
+		return fail("unsupported type")
+	}
+
+	switch f.purpose {
+	case purposeArgument, purposeProperty:
+		if !isScalar(t) {
+			return fail("only basic types can be arguments and properties")
+		}
+	case purposeArguments:
+		if t.kind != kindSlice {
+			return fail("only slices can be used as arguments")
+		}
+	}
+
+	if f.omitEmpty && (t.kind == kindStruct || t.kind == kindValue) {
+		return fail("omitempty is only supported for basic types, pointers and slices")
+	}
+
+	if f.def != nil && t.kind != kindBasic {
+		return fail("default values are only supported for basic types")
+	}
+
+	return f, true, nil
+}
+
+// parseDefaultValue reads the value of a "default=" tag option the same way the kdl package does:
+// as a KDL value, or as a plain string if it is not a valid one.
+func parseDefaultValue(s string) kdl.Value {
+	doc, err := kdl.ParseString("- " + s)
+	if err == nil && len(doc.Nodes) == 1 {
+		n := doc.Nodes[0]
+		if len(n.Args) == 1 && len(n.Props) == 0 && len(n.Children) == 0 {
+			return n.Args[0]
+		}
+	}
+	return kdl.NewStringValue(s, kdl.NoHint())
+}
+
+func (g *generator) structFields(name string) ([]*field, error) {
+
+	st, ok := g.pkg.types[name].(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a struct declared in package %s", name, g.pkg.name)
+	}
+
+	fields := make([]*field, 0, len(st.Fields.List))
+	for _, astField := range st.Fields.List {
+
+		tag := ""
+		if astField.Tag != nil {
+			tag, _ = strconv.Unquote(astField.Tag.Value)
+		}
+
+		if len(astField.Names) == 0 {
+			if value, ok := reflect.StructTag(tag).Lookup("kdl"); ok && value == "-" {
+				continue
+			}
+			return nil, fmt.Errorf("%s: embedded fields are not supported", name)
+		}
+
+		t, _ := g.resolve(astField.Type)
+		for _, ident := range astField.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			f, ok, err := g.parseField(name, ident.Name, t, tag)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+func generate(pkg *sourcePackage, names []string) ([]byte, error) {
+
+	g := &generator{
+		pkg:       pkg,
+		generated: make(map[string]bool, len(names)),
+		imports:   make(map[string]bool),
+	}
+	for _, name := range names {
+		g.generated[name] = true
+	}
+
+	for _, name := range names {
+		fields, err := g.structFields(name)
+		if err != nil {
+			return nil, err
+		}
+		g.marshalMethod(name, fields)
+		if err := g.unmarshalMethod(name, fields); err != nil {
+			return nil, err
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by generate_marshalers. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg.name)
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	for _, imp := range imports {
+		if imp != kdlImportPath {
+			fmt.Fprintf(&out, "\t%q\n", imp)
+		}
+	}
+	fmt.Fprintf(&out, "\n\tkdl %q\n)\n", kdlImportPath)
+	out.Write(g.buf.Bytes())
+
+	return format.Source(out.Bytes())
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func hintExpr(f *field) string {
+	if f.hasHint {
+		return fmt.Sprintf("kdl.Hint(%q)", f.hint)
+	}
+	return "kdl.NoHint()"
+}
+
+// emitToValue writes statements storing the Value of a scalar Go expression in a variable.
+func (g *generator) emitToValue(dst string, src string, t *goType, f *field) {
+
+	hint := hintExpr(f)
+	if t.kind == kindValue {
+		g.printf("%s := %s\n", dst, src)
+		if f.hasHint {
+			g.printf("if %s.Type != kdl.TypeNull {\n%s.TypeHint = %s\n}\n", dst, dst, hint)
+		}
+		return
+	}
+
+	if f.asString {
+		g.imports["strconv"] = true
+		var text string
+		switch kindOf(t.basic) {
+		case "bool":
+			text = fmt.Sprintf("strconv.FormatBool(bool(%s))", src)
+		case "int":
+			text = fmt.Sprintf("strconv.FormatInt(int64(%s), 10)", src)
+		case "uint":
+			text = fmt.Sprintf("strconv.FormatUint(uint64(%s), 10)", src)
+		case "float":
+			text = fmt.Sprintf("strconv.FormatFloat(float64(%s), 'g', -1, 64)", src)
+		}
+		g.printf("%s := kdl.NewStringValue(%s, %s)\n", dst, text, hint)
+		return
+	}
+
+	switch kindOf(t.basic) {
+	case "string":
+		g.printf("%s := kdl.NewStringValue(string(%s), %s)\n", dst, src, hint)
+	case "bool":
+		g.printf("%s := kdl.NewBoolValue(bool(%s), %s)\n", dst, src, hint)
+	case "int":
+		g.imports["math/big"] = true
+		g.printf("%s := kdl.NewIntegerValue(big.NewInt(int64(%s)), %s)\n", dst, src, hint)
+	case "uint":
+		g.imports["math/big"] = true
+		g.printf("%s := kdl.NewIntegerValue(new(big.Int).SetUint64(uint64(%s)), %s)\n", dst, src, hint)
+	case "float":
+		g.imports["math"] = true
+		g.imports["math/big"] = true
+		g.imports["errors"] = true
+		g.printf("if math.IsNaN(float64(%s)) {\nreturn n, errors.New(\"cannot marshal NaN\")\n}\n", src)
+		g.printf("%s := kdl.NewFloatValue(big.NewFloat(float64(%s)), %s)\n", dst, src, hint)
+	}
+}
+
+// kindOf groups builtin types by how they are converted.
+func kindOf(basic string) string {
+	switch basic {
+	case "string", "bool":
+		return basic
+	case "int", "int8", "int16", "int32", "int64", "rune":
+		return "int"
+	case "uint", "uint8", "uint16", "uint32", "uint64", "byte":
+		return "uint"
+	default:
+		return "float"
+	}
+}
+
+// emptyCheck returns a condition that is true if the field is not empty.
+func emptyCheck(f *field, src string) string {
+	switch f.t.kind {
+	case kindPtrStruct:
+		return src + " != nil"
+	case kindSlice, kindRepeated:
+		return "len(" + src + ") > 0"
+	}
+	switch kindOf(f.t.basic) {
+	case "string":
+		return src + ` != ""`
+	case "bool":
+		return src
+	default:
+		return src + " != 0"
+	}
+}
+
+func (g *generator) marshalMethod(name string, fields []*field) {
+
+	g.printf("\n// MarshalKDL implements kdl.Marshaler.\n")
+	g.printf("func (v %s) MarshalKDL() (kdl.Node, error) {\n\n", name)
+	g.printf("n := kdl.NewNode(\"\")\n")
+
+	for _, f := range fields {
+
+		src := "v." + f.goName
+		g.printf("\n// %s\n", f.goName)
+		if f.omitEmpty {
+			g.printf("if %s {\n", emptyCheck(f, src))
+		} else {
+			g.printf("{\n")
+		}
+
+		switch f.purpose {
+		case purposeArgument:
+			g.emitToValue("val", src, f.t, f)
+			g.printf("n.AddArgValue(val)\n")
+		case purposeProperty:
+			g.emitToValue("val", src, f.t, f)
+			g.printf("n.SetPropValue(%q, val)\n", f.name)
+		case purposeArguments:
+			g.printf("for _, e := range %s {\n", src)
+			g.emitToValue("val", "e", f.t.elem, &field{})
+			g.printf("n.AddArgValue(val)\n}\n")
+		case purposeChild:
+			g.marshalChild(f, src)
+		}
+
+		g.printf("}\n")
+	}
+
+	g.printf("\nreturn n, nil\n}\n")
+}
+
+func (g *generator) marshalChild(f *field, src string) {
+
+	// Sets the name of a node returned by a generated method, as well as the hint from the tag
+	finish := func() {
+		g.printf("if len(c.Name) == 0 {\nc.Name = %q\n}\n", f.name)
+		if f.hasHint {
+			g.printf("c.TypeHint = %s\n", hintExpr(f))
+		}
+	}
+
+	switch f.t.kind {
+	case kindBasic, kindValue:
+		g.emitToValue("val", src, f.t, f)
+		g.printf("c := kdl.NewNode(%q)\nc.AddArgValue(val)\nn.AddChild(c)\n", f.name)
+	case kindSlice:
+		g.printf("c := kdl.NewNode(%q)\n", f.name)
+		if f.hasHint {
+			g.printf("c.TypeHint = %s\n", hintExpr(f))
+		}
+		g.printf("for _, e := range %s {\n", src)
+		g.emitToValue("val", "e", f.t.elem, &field{})
+		g.printf("c.AddArgValue(val)\n}\nn.AddChild(c)\n")
+	case kindStruct:
+		g.printf("c, err := %s.MarshalKDL()\nif err != nil {\nreturn n, err\n}\n", src)
+		finish()
+		g.printf("n.AddChild(c)\n")
+	case kindPtrStruct:
+		g.printf("c := kdl.NewNode(%q)\n", f.name)
+		g.printf("if %s == nil {\nc.AddArgValue(kdl.NewNullValue(kdl.NoHint()))\n} else {\n", src)
+		g.printf("var err error\nif c, err = %s.MarshalKDL(); err != nil {\nreturn n, err\n}\n}\n", src)
+		finish()
+		g.printf("n.AddChild(c)\n")
+	case kindRepeated:
+		g.printf("for _, e := range %s {\n", src)
+		g.printf("c, err := e.MarshalKDL()\nif err != nil {\nreturn n, err\n}\n")
+		finish()
+		g.printf("n.AddChild(c)\n}\n")
+	}
+}
+
+// defaultLiteral converts a default value into a Go expression of the field's type.
+func defaultLiteral(f *field) (string, error) {
+
+	val := *f.def
+	var lit string
+	switch kind := kindOf(f.t.basic); {
+	case kind == "string" && val.Type == kdl.TypeString:
+		lit = strconv.Quote(val.StringValue())
+	case kind == "bool" && val.Type == kdl.TypeBool:
+		lit = strconv.FormatBool(val.BoolValue())
+	case (kind == "int" || kind == "uint" || kind == "float") && val.Type == kdl.TypeInteger:
+		lit = val.IntegerValue().String()
+	case kind == "float" && val.Type == kdl.TypeFloat:
+		lit = val.FloatValue().Text('g', -1)
+	default:
+		return "", fmt.Errorf("field %s: default value of type %s does not match %s", f.goName, val.Type, f.t.expr)
+	}
+
+	return fmt.Sprintf("%s(%s)", f.t.expr, lit), nil
+}
+
+// emitFromValue writes statements storing a Value in a Go expression of a scalar type.
+// The value is read with a call to one of the generic accessors, such as `kdl.Prop[%s](n, "key")`.
+func (g *generator) emitFromValue(dst string, t *goType, f *field, val string, accessor string) {
+
+	if f.asString {
+		g.imports["strconv"] = true
+		g.imports["fmt"] = true
+		g.printf("if val := %s; val.Type == kdl.TypeString {\n", val)
+		var parse string
+		switch kindOf(t.basic) {
+		case "bool":
+			parse = "strconv.ParseBool(val.StringValue())"
+		case "int":
+			parse = fmt.Sprintf("strconv.ParseInt(val.StringValue(), 10, %d)", bitSize(t.basic))
+		case "uint":
+			parse = fmt.Sprintf("strconv.ParseUint(val.StringValue(), 10, %d)", bitSize(t.basic))
+		case "float":
+			parse = fmt.Sprintf("strconv.ParseFloat(val.StringValue(), %d)", bitSize(t.basic))
+		}
+		g.printf("x, err := %s\nif err != nil {\n", parse)
+		g.printf("return fmt.Errorf(\"%%w string (expected a number or a boolean): %%w\", kdl.ErrCannotUnmarshal, err)\n}\n")
+		g.printf("%s = %s(x)\n} else {\n", dst, t.expr)
+	}
+
+	g.printf("x, err := "+accessor+"\nif err != nil {\nreturn err\n}\n", t.basic)
+	g.printf("%s = %s(x)\n", dst, t.expr)
+	if f.asString {
+		g.printf("}\n")
+	}
+}
+
+func bitSize(basic string) int {
+	switch basic {
+	case "int8", "uint8", "byte":
+		return 8
+	case "int16", "uint16":
+		return 16
+	case "int32", "uint32", "float32", "rune":
+		return 32
+	case "int", "uint":
+		return 0
+	default:
+		return 64
+	}
+}
+
+func (g *generator) unmarshalMethod(name string, fields []*field) error {
+
+	g.printf("\n// UnmarshalKDL implements kdl.Unmarshaler.\n")
+	g.printf("func (v *%s) UnmarshalKDL(n *kdl.Node) error {\n\n", name)
+
+	for _, f := range fields {
+		if f.def == nil {
+			continue
+		}
+		lit, err := defaultLiteral(f)
+		if err != nil {
+			return fmt.Errorf("%s.%w", name, err)
+		}
+		g.printf("v.%s = %s\n", f.goName, lit)
+	}
+
+	missing := func(f *field, what string, args ...any) {
+		if f.required {
+			g.imports["fmt"] = true
+			g.printf("} else {\nreturn fmt.Errorf(\"%%w: %s of node %%q\", kdl.ErrMissingField, %s)\n",
+				what, strings.Join(append(quoteAll(args), "n.Name"), ", "))
+		}
+	}
+
+	argIndex := 0
+	for _, f := range fields {
+
+		dst := "v." + f.goName
+		g.printf("\n// %s\n", f.goName)
+
+		switch f.purpose {
+		case purposeArgument:
+			g.printf("if len(n.Args) > %d {\n", argIndex)
+			g.emitFromValue(dst, f.t, f, fmt.Sprintf("n.Args[%d]", argIndex), fmt.Sprintf("kdl.Arg[%%s](n, %d)", argIndex))
+			missing(f, "argument %d", argIndex)
+			g.printf("}\n")
+			argIndex++
+		case purposeArguments:
+			g.printf("if len(n.Args) > %d {\n", argIndex)
+			g.printf("%s = make(%s, 0, len(n.Args)-%d)\n", dst, f.t.expr, argIndex)
+			g.printf("for i := %d; i < len(n.Args); i++ {\nvar e %s\n", argIndex, f.t.elem.expr)
+			g.emitFromValue("e", f.t.elem, &field{}, "n.Args[i]", "kdl.Arg[%s](n, i)")
+			g.printf("%s = append(%s, e)\n}\n", dst, dst)
+			missing(f, "argument %d", argIndex)
+			g.printf("}\n")
+		case purposeProperty:
+			g.printf("if n.HasProp(%q) {\n", f.name)
+			g.emitFromValue(dst, f.t, f, fmt.Sprintf("n.Props[%q]", f.name), fmt.Sprintf("kdl.Prop[%%s](n, %q)", f.name))
+			missing(f, "property %q", f.name)
+			g.printf("}\n")
+		case purposeChild:
+			g.unmarshalChild(f, dst)
+		}
+	}
+
+	g.printf("\nreturn nil\n}\n")
+	return nil
+}
+
+func quoteAll(args []any) []string {
+	res := make([]string, len(args))
+	for i, arg := range args {
+		if s, ok := arg.(string); ok {
+			res[i] = strconv.Quote(s)
+		} else {
+			res[i] = fmt.Sprint(arg)
+		}
+	}
+	return res
+}
+
+func (g *generator) unmarshalChild(f *field, dst string) {
+
+	g.imports["fmt"] = true
+	wrap := "return fmt.Errorf(\"node %q: %w\", c.Name, err)\n"
+
+	if f.t.kind == kindRepeated {
+		g.printf("{\nvar elems %s\n", f.t.expr)
+		g.printf("for i := range n.Children {\nc := &n.Children[i]\nif c.Name != %q {\ncontinue\n}\n", f.name)
+		g.printf("var e %s\nif err := e.UnmarshalKDL(c); err != nil {\n%s}\nelems = append(elems, e)\n}\n", f.t.elem.expr, wrap)
+		g.printf("if len(elems) > 0 {\n%s = elems\n", dst)
+		if f.required {
+			g.printf("} else {\nreturn fmt.Errorf(\"%%w: node %%q\", kdl.ErrMissingField, %q)\n", f.name)
+		}
+		g.printf("}\n}\n")
+		return
+	}
+
+	if f.required {
+		g.printf("present := false\n")
+	}
+	g.printf("for i := range n.Children {\nc := &n.Children[i]\nif c.Name != %q {\ncontinue\n}\n", f.name)
+	if f.required {
+		g.printf("present = true\n")
+	}
+
+	switch f.t.kind {
+	case kindBasic, kindValue:
+		g.printf("if len(c.Args) != 1 {\n")
+		g.printf("return fmt.Errorf(\"node %%q: %%w node (expected exactly one argument)\", c.Name, kdl.ErrCannotUnmarshal)\n}\n")
+		g.printf("if err := func() error {\n")
+		g.emitFromValue(dst, f.t, f, "c.Args[0]", "kdl.Arg[%s](c, 0)")
+		g.printf("return nil\n}(); err != nil {\n%s}\n", wrap)
+	case kindSlice:
+		g.printf("%s = make(%s, 0, len(c.Args))\n", dst, f.t.expr)
+		g.printf("for j := range c.Args {\nvar e %s\nif err := func() error {\n", f.t.elem.expr)
+		g.emitFromValue("e", f.t.elem, &field{}, "c.Args[j]", "kdl.Arg[%s](c, j)")
+		g.printf("return nil\n}(); err != nil {\n%s}\n%s = append(%s, e)\n}\n", wrap, dst, dst)
+	case kindStruct:
+		g.printf("if err := %s.UnmarshalKDL(c); err != nil {\n%s}\n", dst, wrap)
+	case kindPtrStruct:
+		g.printf("if len(c.Args) == 1 && len(c.Props) == 0 && len(c.Children) == 0 && c.Args[0].Type == kdl.TypeNull {\n")
+		g.printf("%s = nil\ncontinue\n}\n", dst)
+		g.printf("if %s == nil {\n%s = new(%s)\n}\n", dst, dst, f.t.elem.expr)
+		g.printf("if err := %s.UnmarshalKDL(c); err != nil {\n%s}\n", dst, wrap)
+	}
+
+	g.printf("}\n")
+	if f.required {
+		g.printf("if !present {\nreturn fmt.Errorf(\"%%w: node %%q\", kdl.ErrMissingField, %q)\n}\n", f.name)
+	}
+}
@@ -2,9 +2,11 @@ package kdl
 
 import (
 	"bytes"
+	"encoding"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"reflect"
 	"strconv"
@@ -13,11 +15,55 @@ import (
 	"golang.org/x/exp/slices"
 )
 
+// Marshaler is implemented by types that can convert themselves into a KDL node.
+//
+// If the returned node has no name, the name that would be used
+// for the value (a struct field's or a map key) is assigned to it.
+type Marshaler interface {
+	MarshalKDL() (Node, error)
+}
+
+// ValueMarshaler is implemented by types that can convert themselves into a single KDL Value,
+// so that they can be used as arguments and properties.
+type ValueMarshaler interface {
+	MarshalKDLValue() (Value, error)
+}
+
+var (
+	typeMarshaler      = reflect.TypeOf((*Marshaler)(nil)).Elem()
+	typeValueMarshaler = reflect.TypeOf((*ValueMarshaler)(nil)).Elem()
+	typeTextMarshaler  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// asMarshalInterface returns the value as T, if it (or a pointer to it) implements the interface.
+func asMarshalInterface[T any](v reflect.Value) (T, bool) {
+
+	var zero T
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return zero, false
+	}
+
+	iface := reflect.TypeOf((*T)(nil)).Elem()
+	if v.Type().Implements(iface) && v.CanInterface() {
+		return v.Interface().(T), true
+	}
+
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(iface) {
+		return v.Addr().Interface().(T), true
+	}
+
+	return zero, false
+}
+
 type marshalContext struct {
-	chain []reflect.Value
+	chain       []reflect.Value
+	typeHints   bool // Annotate numbers with the hint matching their Go type.
+	registry    *Registry
+	fields      *fieldCache
+	compareKeys func(a, b string) int // Orders entries of maps by their names.
 }
 
-var errCannotMarshalType = errors.New("cannot marshal type (only structs and maps are supported)")
+var errCannotMarshalType = errors.New("cannot marshal type (only structs, maps, slices and arrays are supported)")
 
 type errMarshalCycleDetected struct {
 	chain []reflect.Value
@@ -32,16 +78,16 @@ func (e *errMarshalCycleDetected) Error() string {
 		return "(cannot get error message as ErrCycleDetected is in invalid state)"
 	}
 
-	n := strings.ToUpper(d.Type().Name())
+	n := strings.ToUpper(d.Type().String())
 	var b strings.Builder
 	b.WriteString("cycle detected when marshalling KDL: ")
 	for i, v := range chain {
 		b.WriteString(strconv.Itoa(i))
 		b.WriteString(") ")
-		if d == v {
+		if isSameReference(d, v) {
 			b.WriteString(n)
 		} else {
-			b.WriteString(v.Type().Name())
+			b.WriteString(v.Type().String())
 		}
 		b.WriteString(" -> ")
 	}
@@ -51,32 +97,89 @@ func (e *errMarshalCycleDetected) Error() string {
 	return b.String()
 }
 
-func marshal(v any) ([]byte, error) {
+// Marshal returns the KDL encoding of v.
+//
+// The value must be a struct, a map with string (or fmt.Stringer) keys, a slice,
+// or a pointer to one of those. Every struct field or map entry
+// becomes a top-level node of the resulting document.
+// Slices of structs or maps are written as a node repeated for every element.
+// Map entries are sorted by their names, so the output is always the same.
+// Values implementing Marshaler are converted by their MarshalKDL method instead,
+// except for v itself, whose fields always become the top-level nodes.
+//
+// Struct fields can be customized with `kdl:"name,option,..."` tags.
+// Fields without a name in their tag are named after the lowercased name of the field,
+// unless an Encoder uses another NameMapper.
+// A name of "-" skips the field. Available options are:
+//
+//	argument   the field is an argument of the node
+//	arguments  elements of a slice field are the remaining arguments of the node (or: args)
+//	property   the field is a property of the node
+//	child      the field is a child node
+//	children   contents of a struct or a map field are the children of the node;
+//	           a []Node field holds raw children not claimed by other fields
+//	name       a string field holds the name of the node
+//	typehint   a string or TypeHint field holds the type hint of the node
+//	props      a map field holds properties not claimed by other fields
+//	omitempty  skip the field if it holds an empty value
+//	inline     merge fields of an embedded struct into the parent
+//	string     write a number or a boolean as a string
+//	hint=name  annotate the value (or the node, for structs and maps) with a type hint
+//
+// Values of time.Time, time.Duration, url.URL, netip.Addr and [16]byte
+// are written as strings annotated with the (date-time), (duration), (url),
+// (ipv4) or (ipv6), and (uuid) type hints reserved by the KDL specification.
+// An unset netip.Addr is written as null. Named [16]byte types implementing
+// encoding.TextMarshaler or encoding.TextUnmarshaler are written as they choose instead.
+// When decoding, (date) and (time) are accepted for time.Time as well.
+//
+// When decoding, the "required" option rejects documents without the field,
+// and the "default=value" option assigns a value (written in KDL syntax)
+// to the field before its struct gets decoded.
+func Marshal(v any) ([]byte, error) {
 	var buf bytes.Buffer
-	var data []byte
-	err := marshalWriter(v, &buf)
-	if err != nil {
-		data = buf.Bytes()
+	if err := MarshalTo(&buf, v); err != nil {
+		return nil, err
 	}
-	return data, err
+	return buf.Bytes(), nil
 }
 
-func marshalWriter(v any, w io.Writer) error {
+// MarshalTo writes the KDL encoding of v to an io.Writer.
+// See Marshal for details.
+func MarshalTo(w io.Writer, v any) error {
+	return NewEncoder(w).Encode(v)
+}
+
+// marshalDocument converts v into a new Document.
+// A Marshaler describes a single node, so v is always converted field by field.
+func marshalDocument(c *marshalContext, v any) (Document, error) {
 
 	doc := NewDocument()
 
-	chain := make([]reflect.Value, 0, 8)
-	c := marshalContext{chain}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
 
-	if err := valueToChildren(&c, reflect.ValueOf(v), &doc); err != nil {
-		return err
+	if err := fieldsToChildren(c, rv, &doc); err != nil {
+		return doc, err
 	}
 
-	return doc.Write(w)
+	return doc, nil
+}
+
+// isSameReference checks if two values are pointers to or maps of the same data.
+func isSameReference(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Pointer, reflect.Map:
+		return a.Kind() == b.Kind() && !a.IsNil() && !b.IsNil() && a.Pointer() == b.Pointer()
+	default:
+		return false
+	}
 }
 
 func tryPushChain(c *marshalContext, v reflect.Value) error {
-	if slices.Contains(c.chain, v) {
+	if slices.ContainsFunc(c.chain, func(e reflect.Value) bool { return isSameReference(e, v) }) {
 		return &errMarshalCycleDetected{chain: c.chain, d: v}
 	}
 	c.chain = append(c.chain, v)
@@ -89,7 +192,23 @@ func popChain(c *marshalContext) error {
 	return nil
 }
 
-func valueToChildren(c *marshalContext, v reflect.Value, p nodeParent) (err error) {
+func valueToChildren(c *marshalContext, v reflect.Value, p nodeParent) error {
+
+	if m, ok := asMarshalInterface[Marshaler](v); ok {
+		n, err := m.MarshalKDL()
+		if err != nil {
+			return err
+		}
+		p.AddChild(n)
+		return nil
+	}
+
+	return fieldsToChildren(c, v, p)
+}
+
+// fieldsToChildren converts the fields, entries or elements of v into nodes,
+// without checking if v is a Marshaler itself.
+func fieldsToChildren(c *marshalContext, v reflect.Value, p nodeParent) (err error) {
 
 	if err = tryPushChain(c, v); err != nil {
 		return
@@ -100,12 +219,11 @@ func valueToChildren(c *marshalContext, v reflect.Value, p nodeParent) (err erro
 		err = structToChildren(c, v, p)
 	case reflect.Map:
 		err = mapToChildren(c, v, p)
-	case reflect.Pointer:
-		elem := v.Elem()
-		if elem.Kind() == reflect.Struct {
-			err = structToChildren(c, elem, p)
-		} else {
-			err = errCannotMarshalType
+	case reflect.Slice, reflect.Array:
+		err = sequenceToChildren(c, v, p)
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			err = valueToChildren(c, v.Elem(), p)
 		}
 	default:
 		err = errCannotMarshalType
@@ -120,163 +238,485 @@ func valueToChildren(c *marshalContext, v reflect.Value, p nodeParent) (err erro
 
 func structToChildren(c *marshalContext, s reflect.Value, p nodeParent) error {
 
-	t := s.Type()
-	structFields := reflect.VisibleFields(t)
-	for _, sf := range structFields {
+	fields := c.fields.structFields(s.Type())
+	for i := range fields {
 
-		v := s.FieldByIndex(sf.Index)
-		name := caserLower.String(sf.Name)
+		f := &fields[i]
+		v, ok := fieldByIndexNoAlloc(s, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(v)) {
+			continue
+		}
 
-		tag, ok := sf.Tag.Lookup("kdl")
-		if ok {
-			opts := strings.Split(tag, ",")
-			if opts[0] == "-" {
-				continue
+		switch {
+		case f.purpose == purposeName || f.purpose == purposeTypeHint || f.purpose == purposeProps:
+			// There is no node to describe at the top level
+			continue
+		case f.purpose == purposeChildren && v.Type() == typeNodes:
+			if err := sequenceToChildren(c, v, p); err != nil {
+				return err
 			}
-			name = opts[0]
+			continue
 		}
 
-		n := NewNode(name)
-		if err := valueIntoNode(c, v, &n); err != nil {
+		if err := fieldToNamedNodes(c, f, v, p); err != nil {
 			return err
 		}
+	}
 
-		p.AddChild(n)
+	return nil
+}
+
+// fieldToNamedNodes adds nodes representing a struct field to the parent.
+func fieldToNamedNodes(c *marshalContext, f *fieldInfo, v reflect.Value, p nodeParent) error {
+
+	if !f.asString && (f.hint.IsAbsent() || isNodeType(v.Type())) {
+		return valueToNamedNodes(c, f.name, f.hint, v, p)
+	}
+
+	val, err := fieldToKDLValue(c, v, f)
+	if err != nil {
+		return err
 	}
 
+	n := NewNode(f.name)
+	n.AddArgValue(val)
+	p.AddChild(n)
 	return nil
 }
 
-type purpose int
+// isNodeType checks if values of this type fill a whole node
+// instead of being written as its single argument.
+func isNodeType(t reflect.Type) bool {
+	return isCompositeType(t) || reflect.PointerTo(t).Implements(typeMarshaler)
+}
 
-const (
-	purposeArgument purpose = iota
-	purposeProperty
-	purposeChildren
-)
+// fieldToKDLValue converts a struct field into a single Value, respecting the field's options.
+func fieldToKDLValue(c *marshalContext, v reflect.Value, f *fieldInfo) (Value, error) {
 
-func structIntoNode(c *marshalContext, s reflect.Value, n *Node) error {
+	if !f.asString {
+		val, err := valueToHintedKDLValue(c, v)
+		if err == nil && f.hint.IsPresent() && val.Type != TypeNull {
+			val = rehint(val, v, f.hint)
+		}
+		return val, err
+	}
 
-	if err := tryPushChain(c, s); err != nil {
-		return err
+	val, err := valueToKDLValue(v)
+	if err != nil {
+		return val, err
+	}
+	if f.hint.IsPresent() {
+		val = rehint(val, v, f.hint)
 	}
 
-	t := s.Type()
-	structFields := reflect.VisibleFields(t)
+	var text string
+	switch val.Type {
+	case TypeBool:
+		text = strconv.FormatBool(val.BoolValue())
+	case TypeInteger:
+		text = val.IntegerValue().String()
+	case TypeFloat:
+		text = val.FloatValue().Text('g', -1)
+	case TypeNaN:
+		text = "NaN"
+	default:
+		return val, nil
+	}
 
-	childrenTaken := false
+	return NewStringValue(text, val.TypeHint), nil
+}
 
-	for _, sf := range structFields {
+// valueToNamedNodes adds a node of the provided name to the parent, filled with the value.
+// If the value is a sequence of structs or maps, a node is added for every element instead.
+// Every added node is annotated with the hint, if it is present.
+func valueToNamedNodes(c *marshalContext, name string, hint TypeHint, v reflect.Value, p nodeParent) error {
 
-		v := s.FieldByIndex(sf.Index)
-		determinedName := caserLower.String(sf.Name)
-		purpose := purposeProperty
-		tag, ok := sf.Tag.Lookup("kdl")
-		if ok {
-			opts := strings.Split(tag, ",")
+	add := func(v reflect.Value) error {
+		n := NewNode(name)
+		if err := valueIntoNode(c, v, &n); err != nil {
+			return err
+		}
+		if hint.IsPresent() {
+			n.TypeHint = hint
+		}
+		p.AddChild(n)
+		return nil
+	}
 
-			if opts[0] == "-" {
-				continue
-			}
+	if !isRepeatedType(v.Type()) {
+		return add(v)
+	}
 
-			determinedName = opts[0]
-			if len(opts) > 1 {
-
-				if slices.Contains(opts[1:], "argument") {
-					purpose = purposeArgument
-				} else if slices.Contains(opts[1:], "children") {
-					if childrenTaken {
-						return errors.New("this struct already defined one of its fields as children")
-					}
-					purpose = purposeChildren
-					childrenTaken = true
-				}
-			}
+	for i := 0; i < v.Len(); i++ {
+		if err := add(v.Index(i)); err != nil {
+			return err
 		}
+	}
+
+	return nil
+}
+
+// sequenceNodeName is the name of nodes representing elements of a slice or an array.
+const sequenceNodeName = "-"
 
-		switch purpose {
+// sequenceToChildren adds a child node for every element of a slice or an array.
+// Elements of a []Node are added as they are.
+func sequenceToChildren(c *marshalContext, v reflect.Value, p nodeParent) error {
+	if v.Type() == typeNodes {
+		for _, n := range v.Interface().([]Node) {
+			p.AddChild(n)
+		}
+		return nil
+	}
+	for i := 0; i < v.Len(); i++ {
+		n := NewNode(sequenceNodeName)
+		if err := valueIntoNode(c, v.Index(i), &n); err != nil {
+			return err
+		}
+		p.AddChild(n)
+	}
+	return nil
+}
+
+// sequenceIntoArgs adds every element of a slice or an array as an argument of the node.
+func sequenceIntoArgs(c *marshalContext, v reflect.Value, n *Node) error {
+	for i := 0; i < v.Len(); i++ {
+		val, err := valueToHintedKDLValue(c, v.Index(i))
+		if err != nil {
+			return err
+		}
+		n.AddArgValue(val)
+	}
+	return nil
+}
+
+var (
+	errMultipleChildrenFields = errors.New("this struct already defined one of its fields as children")
+	errArgumentsNotSequence   = errors.New("only slices and arrays can be used as arguments")
+	errBadMetadataField       = errors.New("only strings can hold node names and type hints, and only maps can hold properties")
+)
+
+func structIntoNode(c *marshalContext, s reflect.Value, n *Node) error {
+
+	childrenTaken := false
+
+	fields := c.fields.structFields(s.Type())
+	for i := range fields {
+
+		f := &fields[i]
+		v, ok := fieldByIndexNoAlloc(s, f.index)
+		if !ok || (f.omitEmpty && isEmptyValue(v)) {
+			continue
+		}
+
+		switch f.purpose {
 		case purposeArgument:
-			val, err := valueToKDLValue(v)
+			val, err := fieldToKDLValue(c, v, f)
 			if err != nil {
 				return err
 			}
-			n.AddArg(val)
+			n.AddArgValue(val)
+		case purposeArguments:
+			if !isSequenceType(v.Type()) {
+				return errArgumentsNotSequence
+			}
+			if err := sequenceIntoArgs(c, v, n); err != nil {
+				return err
+			}
 		case purposeProperty:
-			val, err := valueToKDLValue(v)
+			val, err := fieldToKDLValue(c, v, f)
 			if err != nil {
 				return err
 			}
-			n.SetProp(Identifier(determinedName), val)
+			n.SetPropValue(Identifier(f.name), val)
+		case purposeProps:
+			if err := mapIntoProps(c, v, n); err != nil {
+				return err
+			}
+		case purposeName:
+			if v.Kind() != reflect.String {
+				return errBadMetadataField
+			}
+			if name := v.String(); len(name) > 0 {
+				n.Name = Identifier(name)
+			}
+		case purposeTypeHint:
+			if v.Type() == typeTypeHint {
+				if hint := v.Interface().(TypeHint); hint.IsPresent() {
+					n.TypeHint = hint
+				}
+				break
+			}
+			if v.Kind() != reflect.String {
+				return errBadMetadataField
+			}
+			if hint := v.String(); len(hint) > 0 {
+				n.TypeHint = Hint(hint)
+			}
+		case purposeChild:
+			if err := fieldToNamedNodes(c, f, v, n); err != nil {
+				return err
+			}
 		case purposeChildren:
+			if childrenTaken {
+				return errMultipleChildrenFields
+			}
+			childrenTaken = true
 			if err := valueToChildren(c, v, n); err != nil {
 				return err
 			}
 		}
 	}
 
-	return popChain(c)
+	return nil
+}
+
+// mapIntoProps adds entries of a map as properties of the node.
+// Properties already set by other fields are not replaced.
+func mapIntoProps(c *marshalContext, m reflect.Value, n *Node) error {
+
+	if m.Kind() != reflect.Map {
+		return errBadMetadataField
+	}
+
+	iter := m.MapRange()
+	for iter.Next() {
+
+		key, err := mapKeyToName(iter.Key())
+		if err != nil {
+			return err
+		}
+
+		if n.HasProp(Identifier(key)) {
+			continue
+		}
+
+		val, err := valueToHintedKDLValue(c, iter.Value())
+		if err != nil {
+			return err
+		}
+		n.SetPropValue(Identifier(key), val)
+	}
+
+	return nil
+}
+
+// fieldByIndexNoAlloc returns a nested struct field.
+// Returns false if the field is promoted through a nil embedded pointer.
+func fieldByIndexNoAlloc(s reflect.Value, index []int) (reflect.Value, bool) {
+	v := s
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+var (
+	errNaN              = errors.New("cannot marshal NaN")
+	errInvalidValueKind = errors.New("invalid value kind")
+)
+
+// valueToHintedKDLValue converts a Go value into a single Value,
+// annotating numbers with the hint matching their Go type if the context asks for it.
+func valueToHintedKDLValue(c *marshalContext, v reflect.Value) (Value, error) {
+	val, err := valueToKDLValue(v)
+	if err == nil && c.typeHints && val.TypeHint.IsAbsent() && (val.Type == TypeInteger || val.Type == TypeFloat) {
+		val.TypeHint = numericHint(v)
+	}
+	return val, err
 }
 
 func valueToKDLValue(v reflect.Value) (Value, error) {
 
+	if m, ok := asMarshalInterface[ValueMarshaler](v); ok {
+		return m.MarshalKDLValue()
+	}
+
+	switch v.Type() {
+	case typeValue:
+		return v.Interface().(Value), nil
+	case typeBigInt:
+		if v.IsNil() {
+			return NewNullValue(NoHint()), nil
+		}
+		return NewIntegerValue(v.Interface().(*big.Int), NoHint()), nil
+	case typeBigFloat:
+		if v.IsNil() {
+			return NewNullValue(NoHint()), nil
+		}
+		return NewFloatValue(v.Interface().(*big.Float), NoHint()), nil
+	}
+
+	if val, ok := reservedToKDLValue(v); ok {
+		return val, nil
+	}
+
+	if m, ok := asMarshalInterface[encoding.TextMarshaler](v); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return newInvalidValue(), err
+		}
+		return NewStringValue(string(text), NoHint()), nil
+	}
+
 	switch v.Kind() {
 	case reflect.String:
-		return NewStringValue(v.Interface().(string), NoHint()), nil
+		return NewStringValue(v.String(), NoHint()), nil
 	case reflect.Bool:
-		return NewBoolValue(v.Interface().(bool), NoHint()), nil
+		return NewBoolValue(v.Bool(), NoHint()), nil
 	case reflect.Float32, reflect.Float64:
-		return NewFloatValue(big.NewFloat(v.Float()), NoHint()), nil
+		f := v.Float()
+		if math.IsNaN(f) {
+			return newInvalidValue(), errNaN
+		}
+		return NewFloatValue(big.NewFloat(f), NoHint()), nil
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		return NewIntegerValue(big.NewInt(v.Int()), NoHint()), nil
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		b := new(big.Int)
 		return NewIntegerValue(b.SetUint64(v.Uint()), NoHint()), nil
-	case reflect.Pointer:
-		if v.Pointer() == 0 {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
 			return NewNullValue(NoHint()), nil
 		}
 		return valueToKDLValue(v.Elem())
 	}
-	return newInvalidValue(), errors.New("invalid value kind")
+	return newInvalidValue(), fmt.Errorf("%w: %s", errInvalidValueKind, v.Type())
 }
 
-var errBadMapKey = errors.New("only maps with string or stringer keys are supported")
+var errBadMapKey = errors.New("only maps with string, encoding.TextMarshaler or fmt.Stringer keys are supported")
+
+// mapKeyToName converts a map key to the name of a node.
+func mapKeyToName(k reflect.Value) (string, error) {
+
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+
+	if m, ok := asMarshalInterface[encoding.TextMarshaler](k); ok {
+		text, err := m.MarshalText()
+		if err != nil {
+			return "", err
+		}
+		return string(text), nil
+	}
+
+	if s, ok := asMarshalInterface[fmt.Stringer](k); ok {
+		return s.String(), nil
+	}
 
+	return "", errBadMapKey
+}
+
+// mapToChildren adds nodes representing entries of a map to the parent,
+// ordered by their names with the comparator of the context.
 func mapToChildren(c *marshalContext, m reflect.Value, p nodeParent) error {
 
+	type entry struct {
+		name  string
+		value reflect.Value
+	}
+
+	entries := make([]entry, 0, m.Len())
 	iter := m.MapRange()
 	for iter.Next() {
-
-		k := iter.Key()
-		var name string
-		if k.Kind() == reflect.String {
-			name = k.Interface().(string)
-		} else {
-			s, ok := k.Interface().(fmt.Stringer)
-			if !ok {
-				return errBadMapKey
-			}
-			name = s.String()
+		name, err := mapKeyToName(iter.Key())
+		if err != nil {
+			return err
 		}
+		entries = append(entries, entry{name, iter.Value()})
+	}
 
-		v := iter.Value()
+	cmp := c.compareKeys
+	if cmp == nil {
+		cmp = strings.Compare
+	}
+	slices.SortStableFunc(entries, func(a, b entry) int { return cmp(a.name, b.name) })
 
-		n := NewNode(name)
-		if err := valueIntoNode(c, v, &n); err != nil {
+	for _, e := range entries {
+		if err := valueToNamedNodes(c, e.name, NoHint(), e.value, p); err != nil {
 			return err
 		}
-
-		p.AddChild(n)
 	}
 
 	return nil
 }
 
-func valueIntoNode(c *marshalContext, v reflect.Value, n *Node) error {
+// applyRegisteredType annotates a node holding a value of a registered type with its type hint.
+// Elements of sequences are also renamed to the registered name of their type.
+func applyRegisteredType(c *marshalContext, t reflect.Type, n *Node) {
+	if hint, ok := c.registry.hintFor(t); ok && n.TypeHint.IsAbsent() {
+		n.TypeHint = Hint(string(hint))
+	}
+	if name, ok := c.registry.nameFor(t); ok && n.Name == sequenceNodeName {
+		n.Name = name
+	}
+}
 
-	if err := tryPushChain(c, v); err != nil {
-		return err
+// valueIntoNode fills a node with the contents of a Go value.
+// Scalars become a single argument, while structs and maps fill the whole node.
+// Elements of slices and arrays become arguments, or children named "-" if they are not scalars.
+func valueIntoNode(c *marshalContext, v reflect.Value, n *Node) (err error) {
+
+	if err = tryPushChain(c, v); err != nil {
+		return
+	}
+
+	if m, ok := asMarshalInterface[Marshaler](v); ok {
+		var custom Node
+		custom, err = m.MarshalKDL()
+		if err != nil {
+			return
+		}
+		if len(custom.Name) == 0 {
+			custom.Name = n.Name
+		}
+		*n = custom
+		return popChain(c)
 	}
 
-	return popChain(c)
+	if isScalarType(v.Type()) {
+		var val Value
+		val, err = valueToKDLValue(v)
+		if err == nil {
+			n.AddArgValue(val)
+		}
+	} else {
+		switch v.Kind() {
+		case reflect.Struct:
+			err = structIntoNode(c, v, n)
+		case reflect.Map:
+			err = mapToChildren(c, v, n)
+		case reflect.Slice, reflect.Array:
+			if isCompositeType(v.Type().Elem()) {
+				err = sequenceToChildren(c, v, n)
+			} else {
+				err = sequenceIntoArgs(c, v, n)
+			}
+		case reflect.Pointer, reflect.Interface:
+			if v.IsNil() {
+				n.AddArgValue(NewNullValue(NoHint()))
+			} else if err = valueIntoNode(c, v.Elem(), n); err == nil && v.Kind() == reflect.Interface {
+				applyRegisteredType(c, v.Elem().Type(), n)
+			}
+		default:
+			var val Value
+			val, err = valueToHintedKDLValue(c, v)
+			if err == nil {
+				n.AddArgValue(val)
+			}
+		}
+	}
+
+	if err == nil {
+		err = popChain(c)
+	}
+
+	return
 }
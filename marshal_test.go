@@ -1,6 +1,9 @@
 package kdl
 
 import (
+	"errors"
+	"fmt"
+	"net/netip"
 	"reflect"
 	"testing"
 
@@ -56,3 +59,331 @@ func TestValueConverts(t *testing.T) {
 	assert.NoError(t, err)
 	assert.EqualValues(t, s, v.StringValue())
 }
+
+func TestMarshalsNestedValues(t *testing.T) {
+
+	type server struct {
+		Host string `kdl:",argument"`
+		Port uint16
+		Tags map[string]string `kdl:",children"`
+	}
+
+	type config struct {
+		Name   string
+		Server server
+		Backup *server `kdl:"backup-server"`
+		Limits map[string]int
+	}
+
+	in := config{
+		Name: "prod",
+		Server: server{
+			Host: "example.com",
+			Port: 8080,
+			Tags: map[string]string{"env": "production"},
+		},
+		Limits: map[string]int{"conns": 20},
+	}
+
+	data, err := Marshal(&in)
+	assert.NoError(t, err)
+	assert.Equal(t, `name "prod"
+server "example.com" port=8080 {
+    env "production"
+}
+backup-server null
+limits {
+    conns 20
+}
+`, string(data))
+
+	var out config
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+}
+
+func TestMarshalDetectsCycles(t *testing.T) {
+
+	type node struct {
+		Next *node
+	}
+
+	n := &node{}
+	n.Next = n
+
+	_, err := Marshal(n)
+	var cycleErr *errMarshalCycleDetected
+	assert.ErrorAs(t, err, &cycleErr)
+}
+
+func TestMarshalRejectsScalars(t *testing.T) {
+	_, err := Marshal(42)
+	assert.ErrorIs(t, err, errCannotMarshalType)
+}
+
+type testRoute struct {
+	Path   string
+	Method string
+}
+
+func (r testRoute) MarshalKDL() (Node, error) {
+	n := NewNode("route")
+	n.AddArg(r.Path)
+	n.SetProp("method", r.Method)
+	return n, nil
+}
+
+func (r *testRoute) UnmarshalKDL(n *Node) error {
+	if len(n.Args) != 1 {
+		return errors.New("route must have a path")
+	}
+	r.Path = n.Args[0].StringValue()
+	r.Method = n.GetProp("method").StringValue()
+	return nil
+}
+
+func TestMarshalsMarshaler(t *testing.T) {
+
+	type api struct {
+		Main    testRoute
+		Backups map[string]*testRoute
+	}
+
+	in := api{
+		Main:    testRoute{Path: "/api", Method: "GET"},
+		Backups: map[string]*testRoute{"old": {Path: "/v1", Method: "POST"}},
+	}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `route "/api" method="GET"
+backups {
+    route "/v1" method="POST"
+}
+`, string(data))
+
+	var out struct {
+		Main testRoute `kdl:"route"`
+	}
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in.Main, out.Main)
+	assert.Error(t, Unmarshal([]byte("route"), &out))
+}
+
+type testColor struct {
+	R, G, B uint8
+}
+
+func (c testColor) MarshalKDLValue() (Value, error) {
+	return NewStringValue(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B), Hint("rgb")), nil
+}
+
+func (c *testColor) UnmarshalKDLValue(v Value) error {
+	if hint, _ := v.TypeHint.Get(); hint != "rgb" || v.Type != TypeString {
+		return errors.New("expected an (rgb) string")
+	}
+	_, err := fmt.Sscanf(v.StringValue(), "#%02x%02x%02x", &c.R, &c.G, &c.B)
+	return err
+}
+
+func TestMarshalsValueMarshaler(t *testing.T) {
+
+	type theme struct {
+		Name       string     `kdl:",argument"`
+		Background testColor  `kdl:"bg"`
+		Foreground *testColor `kdl:"fg"`
+	}
+
+	in := struct {
+		Theme theme
+	}{
+		Theme: theme{
+			Name:       "dark",
+			Background: testColor{0x11, 0x22, 0x33},
+			Foreground: &testColor{0xff, 0xff, 0x00},
+		},
+	}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, "theme \"dark\" bg=(rgb)\"#112233\" fg=(rgb)\"#ffff00\"\n", string(data))
+
+	out := in
+	out.Theme = theme{}
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+
+	assert.Error(t, Unmarshal([]byte(`theme bg="#112233"`), &out))
+}
+
+func TestMarshalsTextMarshaler(t *testing.T) {
+
+	type firewall struct {
+		Gateway netip.Addr
+		Backup  *netip.Addr
+		Allowed map[netip.Addr]bool
+	}
+
+	backup := netip.MustParseAddr("10.0.0.2")
+	in := struct {
+		Firewall firewall
+	}{
+		Firewall: firewall{
+			Gateway: netip.MustParseAddr("10.0.0.1"),
+			Backup:  &backup,
+			Allowed: map[netip.Addr]bool{netip.MustParseAddr("::1"): true},
+		},
+	}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `firewall backup=(ipv4)"10.0.0.2" gateway=(ipv4)"10.0.0.1" {
+    allowed {
+        ::1 true
+    }
+}
+`, string(data))
+
+	out := in
+	out.Firewall = firewall{}
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+
+	assert.Error(t, Unmarshal([]byte(`firewall gateway="not an ip"`), &out))
+}
+
+func TestMarshalsSlices(t *testing.T) {
+
+	type server struct {
+		Host  string `kdl:",argument"`
+		Ports []int  `kdl:",arguments"`
+	}
+
+	type cluster struct {
+		Name    string   `kdl:",argument"`
+		Servers []server `kdl:"server"`
+		Aliases []string
+	}
+
+	in := struct {
+		Clusters []cluster `kdl:"cluster"`
+		Ports    []int
+		Matrix   [][2]int
+	}{
+		Clusters: []cluster{
+			{
+				Name: "eu",
+				Servers: []server{
+					{Host: "a.example.com", Ports: []int{80, 443}},
+					{Host: "b.example.com"},
+				},
+				Aliases: []string{"europe"},
+			},
+			{Name: "us"},
+		},
+		Ports:  []int{80, 443},
+		Matrix: [][2]int{{1, 2}, {3, 4}},
+	}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `cluster "eu" {
+    server "a.example.com" 80 443
+    server "b.example.com"
+    aliases "europe"
+}
+cluster "us" {
+    aliases
+}
+ports 80 443
+matrix 1 2
+matrix 3 4
+`, string(data))
+
+	out := in
+	out.Clusters = nil
+	out.Ports = nil
+	out.Matrix = nil
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+
+	assert.ErrorIs(t, Unmarshal([]byte("matrix 1 2 3"), &out), ErrCannotUnmarshal)
+}
+
+func TestMarshalsSequenceElements(t *testing.T) {
+
+	in := []map[string]int{{"a": 1}, {"b": 2}}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, "\"-\" {\n    a 1\n}\n\"-\" {\n    b 2\n}\n", string(data))
+
+	var out []map[string]int
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+}
+
+func TestMarshalsInterfacesWithoutRegistry(t *testing.T) {
+
+	data, err := Marshal(struct{ A any }{A: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "a 1\n", string(data))
+
+	data, err = Marshal(map[string]any{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "a 1\n", string(data))
+}
+
+type testBase struct {
+	ID      int    `kdl:"id"`
+	Comment string `kdl:"comment,omitempty"`
+}
+
+// TestMeta is exported, so that it is not skipped when embedded.
+type TestMeta struct {
+	Owner string
+}
+
+func TestMarshalsTagOptions(t *testing.T) {
+
+	type item struct {
+		testBase `kdl:",inline"`
+		TestMeta
+		Name    string   `kdl:",argument,omitempty"`
+		Price   float64  `kdl:",string"`
+		Count   *int     `kdl:",omitempty"`
+		Tags    []string `kdl:",omitempty"`
+		Visible bool     `kdl:",string"`
+	}
+
+	in := struct {
+		Item    item
+		Nothing *item `kdl:",omitempty"`
+		Limit   uint  `kdl:",string"`
+	}{
+		Item: item{
+			testBase: testBase{ID: 7},
+			TestMeta: TestMeta{Owner: "alice"},
+			Name:     "widget",
+			Price:    9.99,
+			Visible:  true,
+		},
+		Limit: 10,
+	}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `item "widget" id=7 price="9.99" visible="true" {
+    testmeta owner="alice"
+}
+limit "10"
+`, string(data))
+
+	var out = in
+	out.Item = item{}
+	out.Limit = 0
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+
+	assert.ErrorIs(t, Unmarshal([]byte(`item price="cheap"`), &out), ErrCannotUnmarshal)
+}
@@ -0,0 +1,92 @@
+package kdl
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// NameMapper converts the name of a Go struct field into the name of a KDL node or property.
+// It is only used for fields without an explicit name in their `kdl` tag.
+type NameMapper func(field string) string
+
+// Predefined NameMappers. For a field named HTTPMaxConnections, they return:
+//
+//	LowerCase  httpmaxconnections (the default)
+//	KebabCase  http-max-connections
+//	SnakeCase  http_max_connections
+//	CamelCase  httpMaxConnections
+var (
+	LowerCase NameMapper = caserLower.String
+	KebabCase NameMapper = func(field string) string { return joinLowerWords(field, "-") }
+	SnakeCase NameMapper = func(field string) string { return joinLowerWords(field, "_") }
+	CamelCase NameMapper = camelCase
+)
+
+// mapFieldName converts a field name with the provided mapper, or the default one if it is nil.
+func mapFieldName(m NameMapper, field string) string {
+	if m == nil {
+		return LowerCase(field)
+	}
+	return m(field)
+}
+
+// splitWords splits a Go identifier into words, keeping acronyms together,
+// so that HTTPServer2Config becomes HTTP, Server2 and Config.
+func splitWords(s string) []string {
+
+	words := make([]string, 0, 4)
+	start := 0
+	prev := rune(-1)
+
+	for i, r := range s {
+
+		if r == '_' {
+			if i > start {
+				words = append(words, s[start:i])
+			}
+			start = i + 1
+			prev = r
+			continue
+		}
+
+		if i > start && unicode.IsUpper(r) {
+			next, _ := utf8.DecodeRuneInString(s[i+utf8.RuneLen(r):])
+			if !unicode.IsUpper(prev) || unicode.IsLower(next) {
+				words = append(words, s[start:i])
+				start = i
+			}
+		}
+
+		prev = r
+	}
+
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+
+	return words
+}
+
+func joinLowerWords(field string, sep string) string {
+	words := splitWords(field)
+	for i, w := range words {
+		words[i] = caserLower.String(w)
+	}
+	return strings.Join(words, sep)
+}
+
+func camelCase(field string) string {
+	words := splitWords(field)
+	if len(words) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(caserLower.String(words[0]))
+	for _, w := range words[1:] {
+		r, size := utf8.DecodeRuneInString(w)
+		b.WriteRune(unicode.ToUpper(r))
+		b.WriteString(w[size:])
+	}
+	return b.String()
+}
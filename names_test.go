@@ -0,0 +1,67 @@
+package kdl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNameMappers(t *testing.T) {
+
+	cases := []struct {
+		field, lower, kebab, snake, camel string
+	}{
+		{"Name", "name", "name", "name", "name"},
+		{"MaxConnections", "maxconnections", "max-connections", "max_connections", "maxConnections"},
+		{"HTTPServer", "httpserver", "http-server", "http_server", "httpServer"},
+		{"UserID", "userid", "user-id", "user_id", "userID"},
+		{"Server2Config", "server2config", "server2-config", "server2_config", "server2Config"},
+		{"Snake_Field", "snake_field", "snake-field", "snake_field", "snakeField"},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.lower, LowerCase(c.field))
+		assert.Equal(t, c.kebab, KebabCase(c.field))
+		assert.Equal(t, c.snake, SnakeCase(c.field))
+		assert.Equal(t, c.camel, CamelCase(c.field))
+	}
+}
+
+func TestUsesNameMapper(t *testing.T) {
+
+	type pool struct {
+		MaxConnections int
+		IdleTimeout    int    `kdl:"idle"`
+		DriverName     string `kdl:",argument"`
+	}
+
+	in := struct {
+		ConnectionPool pool
+	}{
+		ConnectionPool: pool{MaxConnections: 10, IdleTimeout: 30, DriverName: "pg"},
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.UseNameMapper(KebabCase)
+	assert.NoError(t, e.Encode(in))
+	assert.Equal(t, `connection-pool "pg" idle=30 max-connections=10
+`, b.String())
+
+	var out = in
+	out.ConnectionPool = pool{}
+	d := NewDecoder(&b)
+	d.UseNameMapper(KebabCase)
+	assert.NoError(t, d.DecodeDocument(&out))
+	assert.Equal(t, in, out)
+
+	upper := NameMapper(strings.ToUpper)
+	b.Reset()
+	e = NewEncoder(&b)
+	e.UseNameMapper(upper)
+	assert.NoError(t, e.Encode(in))
+	assert.Equal(t, `CONNECTIONPOOL "pg" MAXCONNECTIONS=10 idle=30
+`, b.String())
+}
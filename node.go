@@ -7,6 +7,7 @@ type Node struct {
 	Args     []Value              // Ordered arguments of the node.
 	Props    map[Identifier]Value // Unordered properties of the node. CAN BE NIL.
 	Children []Node               // Ordered children of the node.
+	Comments Comments             // Comments around the node.
 }
 
 // NewNode creates a new KDL node.
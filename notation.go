@@ -0,0 +1,71 @@
+package kdl
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Notation describes how a Value was written in the document it was parsed from.
+// A Value that still holds the same value is written back the same way.
+type Notation struct {
+	Text        string // Source text of a number, including its sign. Empty for strings.
+	Radix       int    // Radix of a number: 2, 8, 10 or 16. Zero for strings.
+	Underscores bool   // Whether a number contained digit separators.
+	Exponent    bool   // Whether a number had an exponent.
+	Raw         bool   // Whether a string was a raw string.
+
+	value interface{} // Copy of the value the notation was recorded for.
+}
+
+// Notation returns how the Value was written in the document it was parsed from.
+// Returns false if the Value was not read by one of the Parse functions or a SyntaxTree,
+// or if it has been changed since.
+func (v Value) Notation() (Notation, bool) {
+	if v.notation == nil || !v.notation.matches(&v) {
+		return Notation{}, false
+	}
+	return *v.notation, true
+}
+
+// newNumberNotation records the notation of a number read from its source text.
+func newNumberNotation(text string, radix int, value interface{}) *Notation {
+
+	n := &Notation{
+		Text:        text,
+		Radix:       radix,
+		Underscores: strings.ContainsRune(text, '_'),
+		Exponent:    radix == 10 && strings.ContainsAny(text, "eE"),
+	}
+
+	switch v := value.(type) {
+	case *big.Int:
+		n.value = new(big.Int).Set(v)
+	case *big.Float:
+		n.value = new(big.Float).Copy(v)
+	}
+
+	return n
+}
+
+// newRawStringNotation records that a string was read from a raw string.
+func newRawStringNotation(s string) *Notation {
+	return &Notation{Raw: true, value: s}
+}
+
+// matches checks if the Value still holds the value the notation was recorded for.
+func (n *Notation) matches(v *Value) bool {
+
+	switch recorded := n.value.(type) {
+	case *big.Int:
+		i, ok := v.RawValue.(*big.Int)
+		return ok && v.Type == TypeInteger && i.Cmp(recorded) == 0
+	case *big.Float:
+		f, ok := v.RawValue.(*big.Float)
+		return ok && v.Type == TypeFloat && f.Cmp(recorded) == 0 && f.Signbit() == recorded.Signbit()
+	case string:
+		s, ok := v.RawValue.(string)
+		return ok && v.Type == TypeString && s == recorded
+	default:
+		return false
+	}
+}
@@ -0,0 +1,74 @@
+package kdl
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordsNotation(t *testing.T) {
+
+	doc, err := ParseString(`node 0xFF 1_000_000 1.5e3 -0b101 r#"C:\"#`)
+	assert.NoError(t, err)
+
+	args := doc.Nodes[0].Args
+	notation := func(i int) Notation {
+		n, ok := args[i].Notation()
+		assert.True(t, ok)
+		return n
+	}
+	assert.Equal(t, 16, notation(0).Radix)
+	assert.True(t, notation(1).Underscores)
+	assert.True(t, notation(2).Exponent)
+	assert.Equal(t, "-0b101", notation(3).Text)
+	assert.True(t, notation(4).Raw)
+
+	args[0].IntegerValue().SetInt64(1)
+	_, ok := args[0].Notation()
+	assert.False(t, ok)
+
+	_, ok = NewIntegerValue(big.NewInt(255), NoHint()).Notation()
+	assert.False(t, ok)
+}
+
+func TestDecoderDoesNotRecordNotation(t *testing.T) {
+
+	d := NewDecoder(strings.NewReader("node 0xFF r\"raw\"\n"))
+	n, err := d.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, NewIntegerValue(big.NewInt(255), NoHint()), n.Args[0])
+	assert.Equal(t, NewStringValue("raw", NoHint()), n.Args[1])
+}
+
+func TestWritesOriginalNotation(t *testing.T) {
+
+	src := "node 0xFF 0o17 1_000_000 1.5e3 -0b101 1e6 r\"C:\\\" r#\"\"x\"\"# \"a\\tb\"\n"
+
+	doc, err := ParseString(src)
+	assert.NoError(t, err)
+	s, err := doc.WriteString()
+	assert.NoError(t, err)
+	assert.Equal(t, src, s)
+
+	s, err = WriteOptions{Version: V2}.WriteString(&doc)
+	assert.NoError(t, err)
+	assert.Equal(t, "node 0xFF 0o17 1_000_000 1.5e3 -0b101 1e6 #\"C:\\\"# #\"\"x\"\"# \"a\\tb\"\n", s)
+}
+
+func TestWritesChangedValueWithoutNotation(t *testing.T) {
+
+	doc, err := ParseString(`node 0xFF 1_000 r"raw" 0x10`)
+	assert.NoError(t, err)
+
+	args := doc.Nodes[0].Args
+	args[0].IntegerValue().SetInt64(256)
+	args[1] = NewIntegerValue(big.NewInt(1001), NoHint())
+	args[2].RawValue = "changed"
+	args[3].TypeHint = Hint("u8")
+
+	s, err := doc.WriteString()
+	assert.NoError(t, err)
+	assert.Equal(t, "node 256 1001 \"changed\" (u8)0x10\n", s)
+}
@@ -0,0 +1,129 @@
+package kdl
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// numericHint returns the reserved type hint matching the Go type of a number,
+// or an absent hint if the value is not a plain Go number.
+func numericHint(v reflect.Value) TypeHint {
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return NoHint()
+		}
+		v = v.Elem()
+	}
+
+	if isScalarType(v.Type()) {
+		return NoHint()
+	}
+
+	switch v.Kind() {
+	case reflect.Int:
+		return Hint("isize")
+	case reflect.Int8:
+		return Hint("i8")
+	case reflect.Int16:
+		return Hint("i16")
+	case reflect.Int32:
+		return Hint("i32")
+	case reflect.Int64:
+		return Hint("i64")
+	case reflect.Uint, reflect.Uintptr:
+		return Hint("usize")
+	case reflect.Uint8:
+		return Hint("u8")
+	case reflect.Uint16:
+		return Hint("u16")
+	case reflect.Uint32:
+		return Hint("u32")
+	case reflect.Uint64:
+		return Hint("u64")
+	case reflect.Float32:
+		return Hint("f32")
+	case reflect.Float64:
+		return Hint("f64")
+	default:
+		return NoHint()
+	}
+}
+
+// intHintRange holds the bounds of integers described by a reserved type hint.
+type intHintRange struct {
+	min, max *big.Int
+}
+
+func newIntHintRange(bits uint, signed bool) intHintRange {
+	one := big.NewInt(1)
+	if !signed {
+		max := new(big.Int).Lsh(one, bits)
+		return intHintRange{min: new(big.Int), max: max.Sub(max, one)}
+	}
+	max := new(big.Int).Lsh(one, bits-1)
+	min := new(big.Int).Neg(max)
+	return intHintRange{min: min, max: max.Sub(max, one)}
+}
+
+var intHintRanges = map[Identifier]intHintRange{
+	"i8":    newIntHintRange(8, true),
+	"i16":   newIntHintRange(16, true),
+	"i32":   newIntHintRange(32, true),
+	"i64":   newIntHintRange(64, true),
+	"isize": newIntHintRange(strconv.IntSize, true),
+	"u8":    newIntHintRange(8, false),
+	"u16":   newIntHintRange(16, false),
+	"u32":   newIntHintRange(32, false),
+	"u64":   newIntHintRange(64, false),
+	"usize": newIntHintRange(strconv.IntSize, false),
+}
+
+var floatHintMax = map[Identifier]float64{
+	"f32": math.MaxFloat32,
+	"f64": math.MaxFloat64,
+}
+
+var errValueDoesNotFitHint = fmt.Errorf("%w number (value does not fit its type hint)", ErrCannotUnmarshal)
+
+// checkNumericHint verifies that a number fits the range of its reserved type hint, if it has one.
+// Values with other hints, or without any, are always accepted.
+func checkNumericHint(val Value) error {
+
+	if val.Type != TypeInteger && val.Type != TypeFloat && val.Type != TypeNaN {
+		return nil
+	}
+
+	hint, ok := val.TypeHint.Get()
+	if !ok {
+		return nil
+	}
+
+	if r, ok := intHintRanges[hint]; ok {
+		if val.Type != TypeInteger {
+			return fmt.Errorf("%w: expected an integer for (%s)", errValueDoesNotFitHint, hint)
+		}
+		i := val.IntegerValue()
+		if i.Cmp(r.min) < 0 || i.Cmp(r.max) > 0 {
+			return fmt.Errorf("%w: %s is out of range for (%s)", errValueDoesNotFitHint, i, hint)
+		}
+		return nil
+	}
+
+	if max, ok := floatHintMax[hint]; ok && val.Type != TypeNaN {
+		var f *big.Float
+		if val.Type == TypeInteger {
+			f = new(big.Float).SetInt(val.IntegerValue())
+		} else {
+			f = val.FloatValue()
+		}
+		if !f.IsInf() && new(big.Float).Abs(f).Cmp(big.NewFloat(max)) > 0 {
+			return fmt.Errorf("%w: %s is out of range for (%s)", errValueDoesNotFitHint, f.Text('g', -1), hint)
+		}
+	}
+
+	return nil
+}
@@ -10,9 +10,30 @@ import (
 
 //go:generate go run internal/tools/generate_test_cases/generate.go
 
-func parse(br innerReader) (Document, error) {
+// Version selects the version of the KDL specification a document is read as.
+type Version int
+
+const (
+	// Auto reads a document as KDL 2.0.0 if it starts with a `/- kdl-version 2` marker,
+	// and as KDL 1.0.0 otherwise.
+	Auto Version = iota
+	// V1 reads a document as KDL 1.0.0.
+	V1
+	// V2 reads a document as KDL 2.0.0.
+	V2
+)
+
+// ParseOptions configures how documents are parsed.
+// The zero value is ready to use and detects the version of the document.
+type ParseOptions struct {
+	Version Version
+}
+
+func parse(br innerReader, opts ParseOptions) (Document, error) {
 	doc := NewDocument()
 	r := wrapReader(br)
+	r.version = opts.Version
+	r.keepNotations = true
 
 	nodes, err := readNodes(&r)
 	if err != nil {
@@ -20,31 +41,52 @@ func parse(br innerReader) (Document, error) {
 	}
 
 	doc.Nodes = nodes
+	attachDocumentComments(&doc, r.takeComments())
 	return doc, nil
 }
 
-func ParseReader(r io.Reader) (Document, error) {
+// ParseReader parses a document from r.
+func (o ParseOptions) ParseReader(r io.Reader) (Document, error) {
 	br := bufio.NewReader(r)
-	return parse(br)
+	return parse(br, o)
 }
 
-func ParseBytes(b []byte) (Document, error) {
+// ParseBytes parses a document from b.
+func (o ParseOptions) ParseBytes(b []byte) (Document, error) {
 	bb := bytes.NewReader(b)
-	return ParseReader(bb)
+	return o.ParseReader(bb)
 }
 
-func ParseString(s string) (Document, error) {
+// ParseString parses a document from s.
+func (o ParseOptions) ParseString(s string) (Document, error) {
 	sr := strings.NewReader(s)
 	br := bufio.NewReader(sr)
-	return parse(br)
+	return parse(br, o)
 }
 
-func ParseFile(path string) (Document, error) {
+// ParseFile parses a document from the file at path.
+func (o ParseOptions) ParseFile(path string) (Document, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return NewDocument(), err
 	}
 	defer f.Close()
 	br := bufio.NewReader(f)
-	return parse(br)
+	return parse(br, o)
+}
+
+func ParseReader(r io.Reader) (Document, error) {
+	return ParseOptions{}.ParseReader(r)
+}
+
+func ParseBytes(b []byte) (Document, error) {
+	return ParseOptions{}.ParseBytes(b)
+}
+
+func ParseString(s string) (Document, error) {
+	return ParseOptions{}.ParseString(s)
+}
+
+func ParseFile(path string) (Document, error) {
+	return ParseOptions{}.ParseFile(path)
 }
@@ -35,3 +35,77 @@ func BenchmarkParseSimpleDocument(b *testing.B) {
 		_, _ = ParseString(inputSimple)
 	}
 }
+
+const inputV2 string = `/- kdl-version 2
+package name=kdl version = "2.0.0" {
+	flags #true #null #inf
+	(ipv4)server localhost "#"; path #"C:\kdl"#
+	description """
+		Multi-line
+		  string
+		"""
+}
+`
+
+func TestParsesV2Document(t *testing.T) {
+
+	doc, err := ParseString(inputV2)
+	assert.NoError(t, err)
+
+	pkg := doc.Nodes[0]
+	assert.Equal(t, "kdl", pkg.Props["name"].StringValue())
+	assert.Equal(t, "2.0.0", pkg.Props["version"].StringValue())
+
+	flags := pkg.Children[0].Args
+	assert.Equal(t, true, flags[0].BoolValue())
+	assert.Equal(t, TypeNull, flags[1].Type)
+	assert.True(t, flags[2].FloatValue().IsInf())
+
+	server := pkg.Children[1]
+	assert.Equal(t, Hint("ipv4"), server.TypeHint)
+	assert.Equal(t, "localhost", server.Args[0].StringValue())
+	assert.Equal(t, "#", server.Args[1].StringValue())
+	assert.Equal(t, `C:\kdl`, pkg.Children[2].Args[0].StringValue())
+	assert.Equal(t, "Multi-line\n  string", pkg.Children[3].Args[0].StringValue())
+}
+
+func TestParsesSelectedVersion(t *testing.T) {
+
+	_, err := ParseOptions{Version: V1}.ParseString(inputV2)
+	assert.ErrorIs(t, err, ErrInvalidSyntax)
+
+	doc, err := ParseOptions{Version: V2}.ParseString(`node true`)
+	assert.ErrorIs(t, err, ErrInvalidSyntax)
+	assert.Empty(t, doc.Nodes)
+
+	doc, err = ParseString(`node true r"raw"`)
+	assert.NoError(t, err)
+	assert.Equal(t, true, doc.Nodes[0].Args[0].BoolValue())
+	assert.Equal(t, "raw", doc.Nodes[0].Args[1].StringValue())
+}
+
+func TestParsesV2ChildrenWithoutSpace(t *testing.T) {
+
+	doc, err := ParseOptions{Version: V2}.ParseString("node{child;}\nnext a 1 #true k=v{b}")
+	assert.NoError(t, err)
+	if assert.Len(t, doc.Nodes, 2) {
+		assert.Equal(t, Identifier("child"), doc.Nodes[0].Children[0].Name)
+		assert.Len(t, doc.Nodes[1].Args, 3)
+		assert.Equal(t, Identifier("b"), doc.Nodes[1].Children[0].Name)
+	}
+}
+
+func TestParsesCommentAtEndOfNode(t *testing.T) {
+
+	doc, err := ParseString("node 1 // one\nnode 2 \\ // continued\n    3\n")
+	assert.NoError(t, err)
+	assert.Len(t, doc.Nodes, 2)
+	assert.Len(t, doc.Nodes[1].Args, 2)
+}
+
+func TestParsesOneLetterNodeAtEnd(t *testing.T) {
+
+	doc, err := ParseString("node\ne")
+	assert.NoError(t, err)
+	assert.Len(t, doc.Nodes, 2)
+}
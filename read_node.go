@@ -3,6 +3,7 @@ package kdl
 import (
 	"fmt"
 	"io"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -13,16 +14,107 @@ var (
 	errUnexpectedSlashdash    = fmt.Errorf("%w: unexpected slashdash", ErrInvalidSyntax)
 )
 
+// position is a place in the document, as reported by ErrWithPosition.
+type position struct {
+	line   int
+	column int
+}
+
+// currentPosition returns the position the reader is at.
+func currentPosition(r *reader) position {
+	return position{line: r.line, column: r.pos}
+}
+
+// byteRange is a part of the document, from start up to (not including) end, in bytes.
+type byteRange struct {
+	start int
+	end   int
+}
+
+// nodeSpan records where a node and its properties start in the document.
+//
+// It also records the byte offsets of the parts of the node that a SyntaxTree can edit.
+// Offsets of the children block are -1 if the node does not have one.
+type nodeSpan struct {
+	pos      position
+	props    map[Identifier]position
+	children []nodeSpan
+
+	start         int                      // where the type hint or the name starts
+	commentsStart int                      // where the comment lines just above the node start, or -1
+	headEnd       int                      // where the last argument or property ends
+	childrenOpen  int                      // where the '{' is
+	childrenClose int                      // where the '}' is
+	end           int                      // where the terminator ends
+	propValues    map[Identifier]byteRange // where the values of properties are
+}
+
+// childSpan returns the span of the i-th child, if known.
+func (s *nodeSpan) childSpan(i int) *nodeSpan {
+	if s == nil || i >= len(s.children) {
+		return nil
+	}
+	return &s.children[i]
+}
+
+// propPosition returns the position of a property, if known.
+func (s *nodeSpan) propPosition(key Identifier) *position {
+	if s == nil {
+		return nil
+	}
+	if pos, ok := s.props[key]; ok {
+		return &pos
+	}
+	return nil
+}
+
 func readNodes(r *reader) (nodes []Node, err error) {
+	return readNodesSpans(r, nil)
+}
+
+// readNodesSpans reads all nodes on the current depth.
+// If spans is not nil, positions of the nodes are appended to it.
+func readNodesSpans(r *reader, spans *[]nodeSpan) (nodes []Node, err error) {
 
 	nodes = make([]Node, 0, 3)
 
+	for {
+		var node Node
+		var done bool
+		var span *nodeSpan
+		if spans != nil {
+			span = &nodeSpan{}
+		}
+		node, done, err = readNextNode(r, span)
+		if err != nil || done {
+			return
+		}
+		nodes = append(nodes, node)
+		if spans != nil {
+			*spans = append(*spans, *span)
+		}
+	}
+}
+
+// readNextNode reads the next node on the current depth,
+// skipping blank lines, comments and nodes silenced with a slashdash.
+//
+// Returns done = true if there are no more nodes on this depth,
+// ie. the document ended or the parent's children block got closed.
+// If span is not nil, it is filled with positions of the node.
+func readNextNode(r *reader, span *nodeSpan) (node Node, done bool, err error) {
+
+	r.resolveVersion()
+
+	commentsStart := -1
 	for {
 		for {
+			lineComments, lineStart, lineOffset := len(r.comments), r.pos == 0, r.offset
 			err = readUntilSignificant(r, false)
 			if err != nil {
 				if err == io.EOF && r.depth == 0 {
 					err = nil
+					done = true
 				}
 				return
 			}
@@ -32,6 +124,7 @@ func readNodes(r *reader) (nodes []Node, err error) {
 			if err != nil {
 				if err == io.EOF && r.depth == 0 {
 					err = nil
+					done = true
 				}
 				return
 			}
@@ -45,6 +138,7 @@ func readNodes(r *reader) (nodes []Node, err error) {
 						err = errUnexpectedRightBracket
 					}
 					r.discardByte()
+					done = true
 					return
 				} else if ch == '\\' {
 					err = errUnexpectedLineCont
@@ -53,6 +147,16 @@ func readNodes(r *reader) (nodes []Node, err error) {
 				break
 			}
 
+			// Blank lines between comments are kept as empty ones
+			if n := len(r.comments); lineStart && n == lineComments {
+				if n == 0 || r.comments[n-1] != "" {
+					r.comments = append(r.comments, "")
+				}
+				commentsStart = -1
+			} else if lineStart && commentsStart < 0 {
+				commentsStart = lineOffset
+			}
+
 			err = skipUntilNewLine(r, true)
 			if err != nil {
 				return
@@ -63,7 +167,11 @@ func readNodes(r *reader) (nodes []Node, err error) {
 		var slashdash bool
 		slashdash, err = r.isNext(charsSlashDash[:])
 		if err != nil {
-			return
+			// A single character is left, eg. a one-letter node name
+			if err != io.EOF {
+				return
+			}
+			err = nil
 		}
 		if slashdash {
 			r.discardBytes(2)
@@ -77,21 +185,44 @@ func readNodes(r *reader) (nodes []Node, err error) {
 			return
 		}
 
-		var node Node
-		node, err = readNode(r)
+		comments := r.takeComments()
+		if onlyBlankLines(comments) {
+			comments = nil
+		}
+		node, err = readNodeSpan(r, span)
 		if err != nil {
 			return
 		}
 
 		if !slashdash {
-			nodes = append(nodes, node)
+			node.Comments.Before = comments
+			if span != nil {
+				span.commentsStart = commentsStart
+			}
+			return
 		}
+
+		// Comments before a silenced node go to the next one
+		r.comments = comments
 	}
 }
 
 func readNode(r *reader) (Node, error) {
+	return readNodeSpan(r, nil)
+}
 
-	node := NewNode("")
+// readNodeSpan reads a single node.
+// If span is not nil, it is filled with positions of the node.
+//
+// Comments inside the node, other than in its children block, are attached after it.
+func readNodeSpan(r *reader, span *nodeSpan) (node Node, err error) {
+
+	node = NewNode("")
+	defer func() { node.Comments.After = r.takeComments() }()
+	if span != nil {
+		*span = nodeSpan{pos: currentPosition(r), start: r.offset, childrenOpen: -1, childrenClose: -1}
+		defer func() { span.end = r.offset }()
+	}
 
 	hint, err := readMaybeTypeHint(r)
 	if err != nil {
@@ -105,6 +236,9 @@ func readNode(r *reader) (Node, error) {
 	}
 
 	node.Name = name
+	if span != nil {
+		span.headEnd = r.offset
+	}
 
 	for {
 
@@ -135,7 +269,11 @@ func readNode(r *reader) (Node, error) {
 		}
 
 		if isNewLine(ch) {
-			r.discardRunes(1)
+			if crlf, _ := r.isNext(charsCRLF[:]); crlf {
+				r.discardBytes(2)
+			} else {
+				r.discardRunes(1)
+			}
 			if slashdash {
 				return node, errUnexpectedSlashdash
 			}
@@ -152,24 +290,39 @@ func readNode(r *reader) (Node, error) {
 			}
 			return node, nil
 		} else if ch == '{' {
+			open := r.offset
 			r.discardByte()
 			r.depth++
-			children, err := readNodes(r)
+			var childSpans *[]nodeSpan
+			if span != nil && !slashdash {
+				childSpans = &span.children
+			}
+			comments := r.takeComments()
+			children, err := readNodesSpans(r, childSpans)
 			if err != nil {
 				return node, err
 			}
 			r.depth--
+			// Comments after the last child are not kept
+			_ = r.takeComments()
+			r.comments = comments
+			if span != nil && !slashdash {
+				span.childrenOpen = open
+				span.childrenClose = r.offset - 1
+			}
 			if !slashdash {
 				for i := range children {
 					node.AddChild(children[i])
 				}
 			}
-			r.discardByte()
 		} else {
-			err = readArgOrProp(r, &node, slashdash)
+			err = readArgOrProp(r, &node, slashdash, span)
 			if err != nil {
 				return node, err
 			}
+			if span != nil {
+				span.headEnd = r.offset
+			}
 		}
 	}
 }
@@ -182,7 +335,10 @@ var (
 
 // readArgOrProp reads an argument or a property
 // and adds them to the provided Node definition.
-func readArgOrProp(r *reader, dest *Node, discard bool) error {
+// If span is not nil, the position of a property is recorded in it.
+func readArgOrProp(r *reader, dest *Node, discard bool, span *nodeSpan) error {
+
+	start := currentPosition(r)
 
 	hint, err := readMaybeTypeHint(r)
 	if err != nil {
@@ -191,14 +347,18 @@ func readArgOrProp(r *reader, dest *Node, discard bool) error {
 
 	// This can only be a property if there is no type hint at this time
 	if hint.IsAbsent() {
-		i, err, quoted := readIdentifier(r, stopModeEquals)
-		if err == nil {
+		first, _ := r.peekByte()
+		if r.version == V2 {
+			if ok, err := readPropOrIdentArgV2(r, dest, discard, span, start); ok || err != nil {
+				return err
+			}
+		} else if i, err, quoted := readIdentifier(r, stopModeEquals); err == nil {
 			// Identifier read successfully.
 			ch, err := r.peekRune()
 			if err == io.EOF {
 				if quoted {
 					if !discard {
-						dest.AddArg(NewStringValue(string(i), NoHint()))
+						dest.AddArgValue(identArgValue(r, i, first == 'r'))
 					}
 					return nil
 				}
@@ -207,19 +367,20 @@ func readArgOrProp(r *reader, dest *Node, discard bool) error {
 				if isValidValueTerminator(ch) {
 					if quoted {
 						if !discard {
-							dest.AddArgValue(NewStringValue(string(i), NoHint()))
+							dest.AddArgValue(identArgValue(r, i, first == 'r'))
 						}
 						return nil
 					}
 					return errUnexpectedBareIdentifier
 				} else if ch == '=' {
 					r.discardByte()
+					valueStart := r.offset
 					v, err := readValue(r)
 					if err != nil {
 						return err
 					}
 					if !discard {
-						dest.SetPropValue(i, v)
+						setProp(dest, span, i, v, start, byteRange{valueStart, r.offset})
 					}
 					return nil
 				}
@@ -240,7 +401,11 @@ func readArgOrProp(r *reader, dest *Node, discard bool) error {
 
 	ch, err := r.peekRune()
 
-	if err == io.EOF || (err == nil && isValidValueTerminator(ch)) {
+	terminator := isValidValueTerminator
+	if r.version == V2 {
+		terminator = isValidValueTerminatorV2
+	}
+	if err == io.EOF || (err == nil && terminator(ch)) {
 		if !discard {
 			dest.AddArg(v)
 		}
@@ -252,6 +417,114 @@ func readArgOrProp(r *reader, dest *Node, discard bool) error {
 	return errUnexpectedTokenAfterValue
 }
 
+// readPropOrIdentArgV2 reads a property, or an argument that is a string, as defined by KDL 2.0.0.
+// In it, bare identifiers are valid arguments, and a '=' can be surrounded by whitespace.
+//
+// Returns ok = false if what follows is not a string, so it has to be read as another kind of value.
+func readPropOrIdentArgV2(r *reader, dest *Node, discard bool, span *nodeSpan, start position) (ok bool, err error) {
+
+	b, err := r.peekBytes(2)
+	if len(b) == 0 {
+		return false, err
+	}
+
+	i, err, quoted := readIdentifier(r, stopModeEquals)
+	raw := quoted && b[0] == '#'
+	if err != nil {
+		// Not a string, unless it was a malformed one
+		if b[0] == '"' || (len(b) == 2 && b[0] == '#' && (b[1] == '"' || b[1] == '#')) {
+			return true, err
+		}
+		return false, nil
+	}
+
+	spaced, err := skipWhitespace(r)
+	if err == io.EOF {
+		if !discard {
+			dest.AddArgValue(identArgValue(r, i, raw))
+		}
+		return true, nil
+	} else if err != nil {
+		return true, err
+	}
+
+	next, err := r.peekRune()
+	if err != nil {
+		return true, err
+	}
+
+	if next == '=' {
+		r.discardByte()
+		if _, err := skipWhitespace(r); err != nil {
+			if err == io.EOF {
+				err = ErrUnexpectedEOF
+			}
+			return true, err
+		}
+		valueStart := r.offset
+		v, err := readValue(r)
+		if err != nil {
+			return true, err
+		}
+		if !discard {
+			setProp(dest, span, i, v, start, byteRange{valueStart, r.offset})
+		}
+		return true, nil
+	}
+
+	if !spaced && !isValidValueTerminatorV2(next) {
+		return true, errUnexpectedTokenAfterIdentifier
+	}
+
+	if !discard {
+		dest.AddArgValue(identArgValue(r, i, raw))
+	}
+	return true, nil
+}
+
+// identArgValue wraps an identifier read as an argument in a Value,
+// recording whether it was written as a raw string.
+func identArgValue(r *reader, i Identifier, raw bool) Value {
+	if raw {
+		return rawStringValue(r, string(i), NoHint())
+	}
+	return NewStringValue(string(i), NoHint())
+}
+
+// setProp sets a property of the node, recording its position in span, if it is not nil.
+func setProp(dest *Node, span *nodeSpan, key Identifier, v Value, start position, value byteRange) {
+	dest.SetPropValue(key, v)
+	if span != nil {
+		if span.props == nil {
+			span.props = make(map[Identifier]position)
+			span.propValues = make(map[Identifier]byteRange)
+		}
+		span.props[key] = start
+		span.propValues[key] = value
+	}
+}
+
+// skipWhitespace discards whitespace, not including new lines.
+// Returns true if there was any.
+func skipWhitespace(r *reader) (bool, error) {
+
+	skipped := false
+	for {
+
+		ch, err := r.peekRune()
+		if err != nil {
+			return skipped, err
+		}
+
+		if !isWhitespace(ch) {
+			return skipped, nil
+		}
+
+		r.discardBytes(utf8.RuneLen(ch))
+		skipped = true
+	}
+}
+
 // skipUntilNewLine discards the reader to the next new line character OR EOF.
 //
 // If afterBreak is true, the reader is positioned after the newline break.
@@ -292,6 +565,29 @@ func skipUntilNewLine(r *reader, afterBreak bool) error {
 	return nil
 }
 
+// readSingleLineComment reads a comment starting with "//", up to (not including) the next line break.
+func readSingleLineComment(r *reader) (string, error) {
+
+	var text strings.Builder
+	for {
+
+		ch, err := r.peekRune()
+		if err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return text.String(), err
+		}
+
+		if isNewLine(ch) {
+			return text.String(), nil
+		}
+
+		_, _ = r.readRune()
+		text.WriteRune(ch)
+	}
+}
+
 var errSignificantInCont = fmt.Errorf("%w: unexpected significant token in escline", ErrInvalidSyntax)
 
 // readUntilSignificant allows the provided reader to skip whitespace and comments.
@@ -323,12 +619,27 @@ outer:
 
 		// Check for single-line comments
 		if comment, err := r.isNext(charsStartComment[:]); comment && err == nil {
-			r.discardBytes(2)
-			return skipUntilNewLine(r, true)
+			text, err := readSingleLineComment(r)
+			r.comments = append(r.comments, text)
+			if err != nil {
+				return err
+			}
+			// A comment after a line continuation does not end the node
+			if escapedLine {
+				if err := skipUntilNewLine(r, true); err != nil {
+					return err
+				}
+				escapedLine = false
+				continue
+			}
+			// Otherwise, the line break is left for the caller to see
+			return nil
 		}
 
 		// Check for multiline comments
 		if comment, err := r.isNext(charsStartCommentBlock[:]); comment && err == nil {
+			var text strings.Builder
+			text.Write(charsStartCommentBlock[:])
 			r.discardBytes(2)
 			// Per spec, multiline comments can be nested, so we can't do naive ReadString("*/")
 			depth := 1
@@ -342,6 +653,7 @@ outer:
 
 				if start {
 					depth += 1
+					text.Write(charsStartCommentBlock[:])
 					r.discardBytes(2)
 					continue inner
 				}
@@ -352,16 +664,19 @@ outer:
 				}
 
 				if end {
+					text.Write(charsEndCommentBlock[:])
 					r.discardBytes(2)
 					depth -= 1
 					if depth <= 0 {
+						r.comments = append(r.comments, text.String())
 						continue outer
 					} else {
 						continue inner
 					}
 				}
 
-				r.discardByte()
+				b, _ := r.readByte()
+				text.WriteByte(b)
 			}
 		}
 
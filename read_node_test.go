@@ -49,6 +49,15 @@ func TestReadsNodeWithChildren(t *testing.T) {
 	assert.Equal(t, "baz", n.Children[1].Args[0].StringValue())
 }
 
+func TestReadsNodeAfterChildren(t *testing.T) {
+	doc, err := ParseString("foo {\n\tbar\n}\nbaz 1\n")
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(doc.Nodes)) {
+		assert.Equal(t, 1, len(doc.Nodes[0].Children))
+		assert.EqualValues(t, "baz", doc.Nodes[1].Name)
+	}
+}
+
 func TestReadsLineContinuation(t *testing.T) {
 	reader := readerFromString("\"foo\" \\\n\"bar\"")
 	n, err := readNode(&reader)
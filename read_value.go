@@ -35,6 +35,10 @@ var escapeReplacer = strings.NewReplacer(
 
 func readQuotedString(r *reader) (string, error) {
 
+	if r.version == V2 {
+		return readQuotedStringV2(r)
+	}
+
 	str, escapes, err := readQuotedStringInner(r)
 	if err != nil {
 		return str, err
@@ -116,14 +120,17 @@ func readRawString(r *reader) (string, error) {
 		return "", err
 	}
 
-	// A raw string must start with an 'r'
-	if ch != 'r' {
-		return "", errExpectedRawString
+	// A raw string must start with an 'r' (in KDL 2.0.0, there is no 'r')
+	length := 1
+	if r.version != V2 {
+		if ch != 'r' {
+			return "", errExpectedRawString
+		}
+		length++
 	}
 
-	// followed by 0 or more '#' characters
+	// followed by 0 or more '#' characters (1 or more in KDL 2.0.0)
 	leadingPoundCount := 0
-	length := 2
 
 	for {
 
@@ -144,6 +151,21 @@ func readRawString(r *reader) (string, error) {
 		}
 	}
 
+	if r.version == V2 {
+		if leadingPoundCount == 0 {
+			return "", errExpectedRawString
+		}
+		// Three doublequotes start a multi-line raw string
+		if next, _ := r.peekBytes(length + 2); len(next) == length+2 && next[length] == '"' && next[length+1] == '"' {
+			r.discardBytes(length + 2)
+			s, err := readMultiLineStringBody(r, leadingPoundCount)
+			if err != nil {
+				return "", err
+			}
+			return dedentMultiLineString(s)
+		}
+	}
+
 	// The string proper starts now
 	contentStart := length
 	closingPoundCount := 0
@@ -154,6 +176,9 @@ func readRawString(r *reader) (string, error) {
 
 		if isJustAfterDoublequotes && leadingPoundCount == closingPoundCount {
 			s := string(bytes[contentStart : len(bytes)-leadingPoundCount-1])
+			if r.version == V2 && strings.IndexFunc(s, isNewLine) >= 0 {
+				return "", errNewLineInString
+			}
 			r.discardBytes(length)
 			return s, nil
 		}
@@ -285,6 +310,8 @@ var (
 type number struct {
 	Value interface{}
 	Type  TypeTag
+	Text  string // Source text of the number, if notations are kept.
+	Radix int
 }
 
 func readNumber(r *reader) (number, error) {
@@ -306,7 +333,7 @@ func readNumber(r *reader) (number, error) {
 		}
 
 		ch := rune(data[len(data)-1])
-		if ch == ';' || ch == '/' || unicode.IsSpace(ch) {
+		if ch == ';' || ch == '/' || unicode.IsSpace(ch) || (r.version == V2 && (ch == '}' || ch == '{')) {
 			data = data[0 : len(data)-1]
 			break
 		}
@@ -316,6 +343,10 @@ func readNumber(r *reader) (number, error) {
 		return number{}, errEmptyNumber
 	}
 
+	var text string
+	if r.keepNotations {
+		text = string(data)
+	}
 	sign := 0
 	if data[0] == '-' {
 		sign = -1
@@ -372,7 +403,7 @@ func readNumber(r *reader) (number, error) {
 			if sign < 0 {
 				f = f.Neg(f)
 			}
-			return number{Type: TypeFloat, Value: f}, nil
+			return number{Type: TypeFloat, Value: f, Text: text, Radix: base}, nil
 		}
 		if strings.ContainsAny(str, "eE") {
 			str = strings.ToUpper(str)
@@ -385,7 +416,7 @@ func readNumber(r *reader) (number, error) {
 				if sign < 0 {
 					f = f.Neg(f)
 				}
-				return number{Type: TypeFloat, Value: f}, nil
+				return number{Type: TypeFloat, Value: f, Text: text, Radix: base}, nil
 			} else {
 				e, _ := strconv.Atoi(exp)
 				str = man + strings.Repeat("0", e)
@@ -400,7 +431,7 @@ func readNumber(r *reader) (number, error) {
 		if sign < 0 {
 			i = i.Neg(i)
 		}
-		return number{Type: TypeInteger, Value: i}, nil
+		return number{Type: TypeInteger, Value: i, Text: text, Radix: base}, nil
 	}
 
 	return number{}, errFailedToParseInt
@@ -428,7 +459,12 @@ func readBareIdentifier(r *reader, stopMode identStopMode) (Identifier, error) {
 		return "", err
 	}
 
-	if !isAllowedInitialCharacter(ch) {
+	allowed, keyword, numeric := isRuneAllowedInBareIdentifier, isKeyword, startsWithDigit
+	if r.version == V2 {
+		allowed, keyword, numeric = isRuneAllowedInBareIdentifierV2, isKeywordV2, startsLikeNumberV2
+	}
+
+	if !allowed(ch) || unicode.IsDigit(ch) {
 		return "", errInvalidInitialCharInBareIdent
 	}
 
@@ -468,13 +504,16 @@ func readBareIdentifier(r *reader, stopMode identStopMode) (Identifier, error) {
 			break
 		}
 
-		if !isRuneAllowedInBareIdentifier(ch) {
+		if !allowed(ch) {
 			if stopMode == stopModeCloseParen && ch == ')' {
 				break
 			} else if stopMode == stopModeEquals && ch == '=' {
 				break
 			} else if stopMode == stopModeSemicolon && ch == ';' {
 				break
+			} else if r.version == V2 && stopMode != stopModeCloseParen && (ch == ';' || ch == '}' || ch == '{') {
+				// Nodes and values do not need whitespace before a terminator or children
+				break
 			}
 			return "", errInvalidCharInBareIdent
 		}
@@ -489,10 +528,10 @@ func readBareIdentifier(r *reader, stopMode identStopMode) (Identifier, error) {
 
 	// Unsafe string to avoid allocations if this was not a valid identifier
 	ident := unsafe.String(unsafe.SliceData(b), len(b))
-	if isKeyword(ident) {
+	if keyword(ident) {
 		return "", errInvalidBareIdent
 	}
-	if startsWithDigit(ident) {
+	if numeric(ident) {
 		return "", errInvalidBareIdent
 	}
 
@@ -521,8 +560,16 @@ func readIdentifier(r *reader, stopMode identStopMode) (i Identifier, err error,
 		return
 	}
 
+	// # starts a raw string in KDL 2.0.0
+	if ch == '#' && r.version == V2 {
+		quoted = true
+		s, err = readRawString(r)
+		i = Identifier(s)
+		return
+	}
+
 	// r could mean a raw string or a bare ident
-	if ch == 'r' {
+	if ch == 'r' && r.version != V2 {
 		s, err = readRawString(r)
 		if err != nil {
 			i, err = readBareIdentifier(r, stopMode)
@@ -534,12 +581,7 @@ func readIdentifier(r *reader, stopMode identStopMode) (i Identifier, err error,
 		return
 	}
 
-	if isAllowedInitialCharacter(ch) {
-		i, err = readBareIdentifier(r, stopMode)
-	} else {
-		err = errInvalidInitialCharInBareIdent
-	}
-
+	i, err = readBareIdentifier(r, stopMode)
 	return
 }
 
@@ -562,12 +604,21 @@ func readMaybeTypeHint(r *reader) (TypeHint, error) {
 	r.discardByte()
 
 	// An identifier should follow right after - no whitespace nor comments
+	// (KDL 2.0.0 allows them)
+	if err := skipNodeSpaceV2(r); err != nil {
+		return NoHint(), err
+	}
+
 	ident, err, _ := readIdentifier(r, stopModeCloseParen)
 	if err != nil {
 		return NoHint(), err
 	}
 
 	// The parenthesis also should close just after - no whitespace nor comments
+	if err := skipNodeSpaceV2(r); err != nil {
+		return NoHint(), err
+	}
+
 	ch, err = r.peekByte()
 	if err != nil {
 		if err == io.EOF {
@@ -576,12 +627,18 @@ func readMaybeTypeHint(r *reader) (TypeHint, error) {
 		return NoHint(), err
 	}
 
-	if ch == ')' {
-		r.discardByte()
-		return Hint(string(ident)), nil
+	if ch != ')' {
+		return NoHint(), errExpectedCloseHint
+	}
+
+	r.discardByte()
+
+	// KDL 2.0.0 also allows them between the hint and what it annotates
+	if err := skipNodeSpaceV2(r); err != nil {
+		return NoHint(), err
 	}
 
-	return NoHint(), errExpectedCloseHint
+	return Hint(string(ident)), nil
 }
 
 var errExpectedValue = fmt.Errorf("%w: expected value", ErrInvalidSyntax)
@@ -599,19 +656,11 @@ func readValue(r *reader) (Value, error) {
 	}
 
 	if unicode.IsDigit(ch) {
-		n, err := readNumber(r)
-		if err != nil {
-			return newInvalidValue(), err
-		}
+		return readNumberValue(r, hint)
+	}
 
-		switch n.Type {
-		case TypeFloat:
-			return NewFloatValue(n.Value.(*big.Float), hint), nil
-		case TypeInteger:
-			return NewIntegerValue(n.Value.(*big.Int), hint), nil
-		default:
-			return newInvalidValue(), errInvalidNumValue
-		}
+	if r.version == V2 {
+		return readValueV2(r, ch, hint)
 	}
 
 	switch ch {
@@ -628,25 +677,13 @@ func readValue(r *reader) (Value, error) {
 		}
 		return NewBoolValue(v, hint), nil
 	case '-', '+':
-		n, err := readNumber(r)
-		if err != nil {
-			return newInvalidValue(), err
-		}
-
-		switch n.Type {
-		case TypeFloat:
-			return NewFloatValue(n.Value.(*big.Float), hint), nil
-		case TypeInteger:
-			return NewIntegerValue(n.Value.(*big.Int), hint), nil
-		default:
-			return newInvalidValue(), errInvalidNumValue
-		}
+		return readNumberValue(r, hint)
 	case 'r':
 		v, err := readRawString(r)
 		if err != nil {
 			return newInvalidValue(), err
 		}
-		return NewStringValue(v, hint), nil
+		return rawStringValue(r, v, hint), nil
 	case 'n':
 		err := readNull(r)
 		return NewNullValue(hint), err
@@ -654,3 +691,36 @@ func readValue(r *reader) (Value, error) {
 		return newInvalidValue(), errExpectedValue
 	}
 }
+
+// readNumberValue reads a number and wraps it in a Value.
+func readNumberValue(r *reader, hint TypeHint) (Value, error) {
+
+	n, err := readNumber(r)
+	if err != nil {
+		return newInvalidValue(), err
+	}
+
+	var v Value
+	switch n.Type {
+	case TypeFloat:
+		v = NewFloatValue(n.Value.(*big.Float), hint)
+	case TypeInteger:
+		v = NewIntegerValue(n.Value.(*big.Int), hint)
+	default:
+		return newInvalidValue(), errInvalidNumValue
+	}
+
+	if r.keepNotations {
+		v.notation = newNumberNotation(n.Text, n.Radix, n.Value)
+	}
+	return v, nil
+}
+
+// rawStringValue wraps a string read from a raw string in a Value.
+func rawStringValue(r *reader, s string, hint TypeHint) Value {
+	v := NewStringValue(s, hint)
+	if r.keepNotations {
+		v.notation = newRawStringNotation(s)
+	}
+	return v
+}
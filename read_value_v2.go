@@ -0,0 +1,335 @@
+package kdl
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	errNewLineInString       = fmt.Errorf("%w: unescaped new line in a single-line string", ErrInvalidSyntax)
+	errBadEscape             = fmt.Errorf("%w: invalid escape sequence in a string", ErrInvalidSyntax)
+	errBadMultiLineStart     = fmt.Errorf("%w: multi-line string must start with a new line", ErrInvalidSyntax)
+	errBadMultiLineEnd       = fmt.Errorf("%w: multi-line string must end on a line with whitespace only", ErrInvalidSyntax)
+	errBadMultiLineIndent    = fmt.Errorf("%w: multi-line string is not indented like its closing line", ErrInvalidSyntax)
+	errUnexpectedBareKeyword = fmt.Errorf("%w: unknown keyword", ErrInvalidSyntax)
+)
+
+var charsMultiLineQuote = [...]byte{'"', '"', '"'}
+
+// readQuotedStringV2 reads a quoted string or a multi-line string, as defined by KDL 2.0.0.
+func readQuotedStringV2(r *reader) (string, error) {
+
+	if multiline, err := r.isNext(charsMultiLineQuote[:]); multiline && err == nil {
+		r.discardBytes(len(charsMultiLineQuote))
+		s, err := readMultiLineStringBody(r, 0)
+		if err != nil {
+			return "", err
+		}
+		s, err = dedentMultiLineString(resolveWhitespaceEscapes(s))
+		if err != nil {
+			return "", err
+		}
+		return unescapeStringV2(s)
+	}
+
+	str, escapes, err := readQuotedStringInner(r)
+	if err != nil {
+		return str, err
+	}
+
+	if escapes {
+		str = resolveWhitespaceEscapes(str)
+	}
+
+	if strings.IndexFunc(str, isNewLine) >= 0 {
+		return "", errNewLineInString
+	}
+
+	if escapes {
+		return unescapeStringV2(str)
+	}
+	return str, nil
+}
+
+// readMultiLineStringBody reads the contents of a multi-line string, just after its opening quotes,
+// until the closing quotes followed by the provided number of '#' characters.
+// Escapes are left as they are, unless it is a raw string, in which case there are none.
+func readMultiLineStringBody(r *reader, pounds int) (string, error) {
+
+	closing := append(charsMultiLineQuote[:2:2], strings.Repeat("#", pounds)...)
+
+	var b strings.Builder
+	for {
+
+		ch, err := r.readRune()
+		if err != nil {
+			if err == io.EOF {
+				err = errUnexpectedEOFInsideString
+			}
+			return "", err
+		}
+
+		if ch == '\\' && pounds == 0 {
+			// The escaped character cannot close the string
+			b.WriteRune(ch)
+			ch, err = r.readRune()
+			if err != nil {
+				if err == io.EOF {
+					err = errUnexpectedEOFInsideString
+				}
+				return "", err
+			}
+		} else if ch == '"' {
+			if end, _ := r.isNext(closing); end {
+				r.discardBytes(len(closing))
+				return b.String(), nil
+			}
+		}
+
+		b.WriteRune(ch)
+	}
+}
+
+// dedentMultiLineString removes the first and the last line break of a multi-line string,
+// along with the indentation of its closing line from every line.
+// Line breaks are normalized to LF.
+func dedentMultiLineString(s string) (string, error) {
+
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.Map(func(r rune) rune {
+		if isNewLine(r) {
+			return '\n'
+		}
+		return r
+	}, s)
+
+	s, ok := strings.CutPrefix(s, "\n")
+	if !ok {
+		return "", errBadMultiLineStart
+	}
+
+	lines := strings.Split(s, "\n")
+	if len(lines) == 1 {
+		// The closing quotes are on the line just after the opening ones
+		if !isWhitespaceOnly(lines[0]) {
+			return "", errBadMultiLineStart
+		}
+		return "", nil
+	}
+
+	indent := lines[len(lines)-1]
+	if !isWhitespaceOnly(indent) {
+		return "", errBadMultiLineEnd
+	}
+
+	lines = lines[:len(lines)-1]
+	for i, line := range lines {
+		if isWhitespaceOnly(line) {
+			lines[i] = ""
+			continue
+		}
+		dedented, ok := strings.CutPrefix(line, indent)
+		if !ok {
+			return "", errBadMultiLineIndent
+		}
+		lines[i] = dedented
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+func isWhitespaceOnly(s string) bool {
+	for _, r := range s {
+		if !isWhitespace(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveWhitespaceEscapes removes every backslash followed by whitespace or new lines,
+// together with all that whitespace. Other escapes are left as they are.
+func resolveWhitespaceEscapes(s string) string {
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		next, size := utf8.DecodeRuneInString(s[i+1:])
+		if !isWhitespace(next) && !isNewLine(next) {
+			// Keep this escape, including the escaped character
+			b.WriteByte('\\')
+			b.WriteString(s[i+1 : i+1+size])
+			i += size
+			continue
+		}
+
+		rest := strings.TrimLeftFunc(s[i+1:], func(r rune) bool {
+			return isWhitespace(r) || isNewLine(r)
+		})
+		i = len(s) - len(rest) - 1
+	}
+
+	return b.String()
+}
+
+var escapesV2 = map[byte]byte{
+	'n':  '\n',
+	'r':  '\r',
+	't':  '\t',
+	'\\': '\\',
+	'"':  '"',
+	'b':  '\b',
+	'f':  '\f',
+	's':  ' ',
+}
+
+// unescapeStringV2 resolves escape sequences defined by KDL 2.0.0,
+// except for whitespace escapes, which have to be resolved first.
+func unescapeStringV2(s string) (string, error) {
+
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		if i+1 >= len(s) {
+			return "", errBadEscape
+		}
+
+		i++
+		if ch, ok := escapesV2[s[i]]; ok {
+			b.WriteByte(ch)
+			continue
+		}
+
+		if s[i] != 'u' || !strings.HasPrefix(s[i+1:], "{") {
+			return "", errBadEscape
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 3 || end > 8 {
+			return "", errBadEscape
+		}
+
+		code, err := strconv.ParseUint(s[i+2:i+end], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return "", errBadEscape
+		}
+
+		b.WriteRune(rune(code))
+		i += end
+	}
+
+	return b.String(), nil
+}
+
+// keywordsV2Values are the values of keywords defined by KDL 2.0.0.
+var keywordsV2Values = [...]struct {
+	text  string
+	value func(hint TypeHint) Value
+}{
+	{"#true", func(hint TypeHint) Value { return NewBoolValue(true, hint) }},
+	{"#false", func(hint TypeHint) Value { return NewBoolValue(false, hint) }},
+	{"#null", func(hint TypeHint) Value { return NewNullValue(hint) }},
+	{"#inf", func(hint TypeHint) Value { return NewFloatValue(new(big.Float).SetInf(false), hint) }},
+	{"#-inf", func(hint TypeHint) Value { return NewFloatValue(new(big.Float).SetInf(true), hint) }},
+	{"#nan", func(hint TypeHint) Value { return NewNaNValue(hint) }},
+}
+
+// readKeywordV2 reads one of the keywords defined by KDL 2.0.0, such as #true.
+func readKeywordV2(r *reader, hint TypeHint) (Value, error) {
+
+	for _, k := range keywordsV2Values {
+
+		next, err := r.isNext([]byte(k.text))
+		if err != nil && err != io.EOF {
+			return newInvalidValue(), err
+		}
+		if !next {
+			continue
+		}
+
+		// The keyword must not be just a prefix of something longer
+		after, err := r.peekBytes(len(k.text) + 1)
+		if err == nil {
+			ch, _ := utf8.DecodeLastRune(after)
+			if !isValidValueTerminatorV2(ch) && ch != utf8.RuneError {
+				return newInvalidValue(), errUnexpectedBareKeyword
+			}
+		}
+
+		r.discardBytes(len(k.text))
+		return k.value(hint), nil
+	}
+
+	return newInvalidValue(), errUnexpectedBareKeyword
+}
+
+// readValueV2 reads a value that is not a number starting with a digit,
+// as defined by KDL 2.0.0. In it, bare identifiers are strings too.
+func readValueV2(r *reader, ch rune, hint TypeHint) (Value, error) {
+
+	switch ch {
+	case '"':
+		v, err := readQuotedString(r)
+		if err != nil {
+			return newInvalidValue(), err
+		}
+		return NewStringValue(v, hint), nil
+	case '#':
+		// Either a raw string, such as #"foo"#, or a keyword
+		if b, _ := r.peekBytes(2); len(b) == 2 && (b[1] == '"' || b[1] == '#') {
+			v, err := readRawString(r)
+			if err != nil {
+				return newInvalidValue(), err
+			}
+			return rawStringValue(r, v, hint), nil
+		}
+		return readKeywordV2(r, hint)
+	case '-', '+', '.':
+		b, _ := r.peekBytes(3)
+		if startsLikeNumberV2(string(b)) {
+			return readNumberValue(r, hint)
+		}
+	}
+
+	i, err := readBareIdentifier(r, stopModeFreestanding)
+	if err != nil {
+		if err == errInvalidInitialCharInBareIdent {
+			err = errExpectedValue
+		}
+		return newInvalidValue(), err
+	}
+
+	return NewStringValue(string(i), hint), nil
+}
+
+// skipNodeSpaceV2 skips whitespace and comments where KDL 2.0.0 allows them, but KDL 1.0.0 does not.
+func skipNodeSpaceV2(r *reader) error {
+
+	if r.version != V2 {
+		return nil
+	}
+
+	err := readUntilSignificant(r, true)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package kdl
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func readerFromStringV2(s string) reader {
+	r := readerFromString(s)
+	r.version = V2
+	return r
+}
+
+func TestReadsQuotedStringV2(t *testing.T) {
+
+	reader := readerFromStringV2(`"tab\there\s\u{1F600}""a \
+	    b""\/""line
+break"`)
+
+	s, err := readQuotedString(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "tab\there 😀", s)
+
+	s, err = readQuotedString(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "a b", s)
+
+	_, err = readQuotedString(&reader)
+	assert.ErrorIs(t, err, errBadEscape)
+
+	_, err = readQuotedString(&reader)
+	assert.ErrorIs(t, err, errNewLineInString)
+}
+
+func TestReadsMultiLineString(t *testing.T) {
+
+	reader := readerFromStringV2("\"\"\"\n    foo\n\n      bar \\\"\"\" \\\n    baz\n    \"\"\"")
+	s, err := readQuotedString(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\n\n  bar \"\"\" baz", s)
+
+	reader = readerFromStringV2("\"\"\"\r\n  crlf\r\n  \"\"\"")
+	s, err = readQuotedString(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "crlf", s)
+
+	reader = readerFromStringV2("\"\"\"foo\n\"\"\"")
+	_, err = readQuotedString(&reader)
+	assert.ErrorIs(t, err, errBadMultiLineStart)
+
+	reader = readerFromStringV2("\"\"\"\n  foo\n  bar\"\"\"")
+	_, err = readQuotedString(&reader)
+	assert.ErrorIs(t, err, errBadMultiLineEnd)
+
+	reader = readerFromStringV2("\"\"\"\n foo\n  \"\"\"")
+	_, err = readQuotedString(&reader)
+	assert.ErrorIs(t, err, errBadMultiLineIndent)
+}
+
+func TestReadsRawStringV2(t *testing.T) {
+
+	reader := readerFromStringV2(`#"C:\path"#` + `##"say "#hi"#"##`)
+
+	s, err := readRawString(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, `C:\path`, s)
+
+	s, err = readRawString(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, `say "#hi"#`, s)
+
+	reader = readerFromStringV2("#\"\"\"\n  \\n \"\"\n  \"\"\"#")
+	s, err = readRawString(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, `\n ""`, s)
+
+	reader = readerFromStringV2(`r#"v1"#`)
+	_, err = readRawString(&reader)
+	assert.ErrorIs(t, err, errExpectedRawString)
+}
+
+func TestReadsValueV2(t *testing.T) {
+
+	reader := readerFromStringV2(`#true (i8)#null #-inf #nan bare -5 (u8)#"raw"# #truex`)
+
+	value, err := readValue(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, NewBoolValue(true, NoHint()), value)
+
+	_ = readUntilSignificant(&reader, true)
+	value, err = readValue(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, NewNullValue(Hint("i8")), value)
+
+	_ = readUntilSignificant(&reader, true)
+	value, err = readValue(&reader)
+	assert.NoError(t, err)
+	assert.True(t, value.FloatValue().IsInf())
+	assert.Equal(t, -1, value.FloatValue().Sign())
+
+	_ = readUntilSignificant(&reader, true)
+	value, err = readValue(&reader)
+	assert.NoError(t, err)
+	assert.True(t, value.IsNaN())
+	assert.Equal(t, TypeNaN, value.Type)
+
+	_ = readUntilSignificant(&reader, true)
+	value, err = readValue(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, NewStringValue("bare", NoHint()), value)
+
+	_ = readUntilSignificant(&reader, true)
+	value, err = readValue(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, NewIntegerValue(big.NewInt(-5), NoHint()), value)
+
+	_ = readUntilSignificant(&reader, true)
+	value, err = readValue(&reader)
+	assert.NoError(t, err)
+	assert.Equal(t, NewStringValue("raw", Hint("u8")), value)
+
+	_ = readUntilSignificant(&reader, true)
+	_, err = readValue(&reader)
+	assert.ErrorIs(t, err, errUnexpectedBareKeyword)
+}
+
+func TestReadsBareIdentifierV2(t *testing.T) {
+
+	for _, valid := range []string{"<x>,y", "-foo", "--1", "-", ".foo", "truex"} {
+		reader := readerFromStringV2(valid)
+		i, err := readBareIdentifier(&reader, stopModeFreestanding)
+		assert.NoError(t, err, valid)
+		assert.Equal(t, Identifier(valid), i)
+	}
+
+	for _, invalid := range []string{"true", "nan", "-inf", ".5", "-.5", "a#b"} {
+		reader := readerFromStringV2(invalid)
+		_, err := readBareIdentifier(&reader, stopModeFreestanding)
+		assert.ErrorIs(t, err, ErrInvalidSyntax, invalid)
+	}
+}
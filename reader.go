@@ -3,6 +3,7 @@ package kdl
 import (
 	"bytes"
 	"io"
+	"regexp"
 )
 
 type innerReader interface {
@@ -13,10 +14,17 @@ type innerReader interface {
 }
 
 type reader struct {
-	reader innerReader
-	line   int
-	pos    int
-	depth  int
+	reader  innerReader
+	line    int
+	pos     int
+	offset  int // in bytes, from the start of the document
+	depth   int
+	version Version
+
+	// comments read so far, waiting to be attached to a node
+	comments []string
+	// whether to record the notation of values on them
+	keepNotations bool
 }
 
 func wrapReader(r innerReader) reader {
@@ -25,11 +33,12 @@ func wrapReader(r innerReader) reader {
 
 func (r *reader) readRune() (ch rune, err error) {
 
-	ch, _, err = r.reader.ReadRune()
+	ch, size, err := r.reader.ReadRune()
 	if err != nil {
 		return
 	}
 
+	r.offset += size
 	if isNewLine(ch) {
 
 		if ch == '\r' {
@@ -62,6 +71,9 @@ func (r *reader) discardRunes(count int) {
 
 func (r *reader) readByte() (b byte, err error) {
 	b, err = r.reader.ReadByte()
+	if err == nil {
+		r.offset++
+	}
 	if b == '\n' || b == '\r' {
 		r.line++
 		r.pos = 0
@@ -108,7 +120,8 @@ func (r *reader) discardBytes(count int) {
 		r.pos++
 	}
 
-	r.reader.Discard(count)
+	discarded, _ := r.reader.Discard(count)
+	r.offset += discarded
 }
 
 // peekBytes tries to return next N bytes without advancing the reader.
@@ -135,3 +148,30 @@ func (r *reader) isNext(expected []byte) (bool, error) {
 
 	return bytes.Equal(next, expected), nil
 }
+
+// versionMarker matches the `/- kdl-version N` node a document can start with.
+var versionMarker = regexp.MustCompile(`^\x{FEFF}?[\t ]*/-[\t ]*kdl-version[\t ]+([12])[\t ]*(?:[;\r\n\f\x{85}\x{2028}\x{2029}]|/[/*]|$)`)
+
+// resolveVersion decides which version of the specification the document is read as,
+// if it has not been selected explicitly.
+func (r *reader) resolveVersion() {
+
+	if r.version != Auto {
+		return
+	}
+
+	r.version = V1
+
+	// The marker is short, so it has to fit in what is already buffered
+	b, _ := r.peekBytes(64)
+	if m := versionMarker.FindSubmatch(b); m != nil && m[1][0] == '2' {
+		r.version = V2
+	}
+}
+
+// takeComments returns the comments read since the last call, if any.
+func (r *reader) takeComments() []string {
+	c := r.comments
+	r.comments = nil
+	return c
+}
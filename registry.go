@@ -0,0 +1,120 @@
+package kdl
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Registry maps type hints and node names to concrete Go types,
+// so that nodes can be decoded into interface values, such as:
+//
+//	type Plugin interface { Start() error }
+//
+//	registry := kdl.NewRegistry()
+//	registry.RegisterHint("http", &HTTPListener{})
+//	registry.RegisterHint("grpc", &GRPCListener{})
+//
+// A Decoder using this registry stores `(http)listener port=80` in a Plugin field
+// as a *HTTPListener, and an Encoder using it writes the (http) hint back.
+//
+// Types have to be registered before the Registry is used by an Encoder or a Decoder.
+type Registry struct {
+	byHint map[Identifier][]reflect.Type
+	byName map[Identifier][]reflect.Type
+	hints  map[reflect.Type]Identifier
+	names  map[reflect.Type]Identifier
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		byHint: make(map[Identifier][]reflect.Type),
+		byName: make(map[Identifier][]reflect.Type),
+		hints:  make(map[reflect.Type]Identifier),
+		names:  make(map[reflect.Type]Identifier),
+	}
+}
+
+// RegisterHint associates a type hint with the type of the example value.
+// Nodes annotated with the hint are decoded into new values of that type,
+// and values of that type are written with the hint.
+//
+// The same hint can be registered for multiple types,
+// as long as they are stored in different interfaces.
+func (r *Registry) RegisterHint(hint string, example any) {
+	t := registeredType(example)
+	r.byHint[Identifier(hint)] = append(r.byHint[Identifier(hint)], t)
+	r.hints[t] = Identifier(hint)
+}
+
+// RegisterName associates a node name with the type of the example value.
+// Nodes of that name are decoded into new values of that type,
+// and elements of slices holding values of that type are written as nodes of that name.
+func (r *Registry) RegisterName(name string, example any) {
+	t := registeredType(example)
+	r.byName[Identifier(name)] = append(r.byName[Identifier(name)], t)
+	r.names[t] = Identifier(name)
+}
+
+func registeredType(example any) reflect.Type {
+	if example == nil {
+		panic("kdl: cannot register the type of a nil value")
+	}
+	return reflect.TypeOf(example)
+}
+
+// lookup finds the registered type a node should be decoded into,
+// if it is to be stored in an interface of the provided type.
+// The type hint of the node takes precedence over its name.
+func (r *Registry) lookup(n *Node, iface reflect.Type) (reflect.Type, bool) {
+
+	if r == nil {
+		return nil, false
+	}
+
+	if hint, ok := n.TypeHint.Get(); ok {
+		if t, ok := firstAssignable(r.byHint[hint], iface); ok {
+			return t, true
+		}
+	}
+
+	return firstAssignable(r.byName[n.Name], iface)
+}
+
+func firstAssignable(types []reflect.Type, iface reflect.Type) (reflect.Type, bool) {
+	for _, t := range types {
+		if t.AssignableTo(iface) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// hintFor returns the type hint registered for the type, or for the type it points to.
+func (r *Registry) hintFor(t reflect.Type) (Identifier, bool) {
+	if r == nil {
+		return "", false
+	}
+	return registeredFor(r.hints, t)
+}
+
+// nameFor returns the node name registered for the type, or for the type it points to.
+func (r *Registry) nameFor(t reflect.Type) (Identifier, bool) {
+	if r == nil {
+		return "", false
+	}
+	return registeredFor(r.names, t)
+}
+
+func registeredFor(m map[reflect.Type]Identifier, t reflect.Type) (Identifier, bool) {
+	if id, ok := m[t]; ok {
+		return id, true
+	}
+	if t.Kind() == reflect.Pointer {
+		id, ok := m[t.Elem()]
+		return id, ok
+	}
+	return "", false
+}
+
+var errNoRegisteredType = fmt.Errorf("%w node into an interface (no type registered for its name or type hint)", ErrCannotUnmarshal)
@@ -0,0 +1,142 @@
+package kdl
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testPlugin interface {
+	Kind() string
+}
+
+type testHTTPListener struct {
+	Port int
+	TLS  bool `kdl:",omitempty"`
+}
+
+func (*testHTTPListener) Kind() string { return "http" }
+
+type testGRPCListener struct {
+	Port    int
+	Reflect bool
+}
+
+func (testGRPCListener) Kind() string { return "grpc" }
+
+func newTestRegistry() *Registry {
+	r := NewRegistry()
+	r.RegisterHint("http", &testHTTPListener{})
+	r.RegisterHint("grpc", testGRPCListener{})
+	r.RegisterName("cache", testGRPCListener{})
+	return r
+}
+
+func TestDecodesRegisteredTypes(t *testing.T) {
+
+	var cfg struct {
+		Main      testPlugin
+		Listeners []testPlugin `kdl:"listener"`
+	}
+
+	d := NewDecoder(bytes.NewReader([]byte(`
+		(grpc)main port=7000 reflect=true
+		(http)listener port=80
+		(grpc)listener port=9000
+	`)))
+	d.UseRegistry(newTestRegistry())
+	assert.NoError(t, d.DecodeDocument(&cfg))
+
+	assert.Equal(t, testGRPCListener{Port: 7000, Reflect: true}, cfg.Main)
+	assert.Equal(t, []testPlugin{
+		&testHTTPListener{Port: 80},
+		testGRPCListener{Port: 9000},
+	}, cfg.Listeners)
+}
+
+func TestDecodesRegisteredNames(t *testing.T) {
+
+	type pipeline struct {
+		Plugins []testPlugin `kdl:",children"`
+	}
+
+	var cfg struct {
+		Pipeline pipeline
+	}
+
+	d := NewDecoder(bytes.NewReader([]byte(`pipeline {
+		cache port=6379
+		(http)- port=8080
+	}`)))
+	d.UseRegistry(newTestRegistry())
+	assert.NoError(t, d.DecodeDocument(&cfg))
+
+	assert.Equal(t, []testPlugin{
+		testGRPCListener{Port: 6379},
+		&testHTTPListener{Port: 8080},
+	}, cfg.Pipeline.Plugins)
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.UseRegistry(newTestRegistry())
+	assert.NoError(t, e.Encode(cfg))
+	assert.Equal(t, `pipeline {
+    (grpc)cache port=6379 reflect=false
+    (http)"-" port=8080
+}
+`, b.String())
+}
+
+func TestRejectsUnregisteredTypes(t *testing.T) {
+
+	var cfg struct {
+		Main testPlugin
+	}
+
+	d := NewDecoder(bytes.NewReader([]byte("\n(ftp)main port=21")))
+	d.UseRegistry(newTestRegistry())
+	err := d.DecodeDocument(&cfg)
+
+	var posErr *ErrWithPosition
+	if assert.ErrorAs(t, err, &posErr) {
+		assert.Equal(t, 2, posErr.Line)
+	}
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+
+	// Without a registry, nothing can be decoded
+	assert.ErrorIs(t, Unmarshal([]byte("(http)main port=80"), &cfg), ErrCannotUnmarshal)
+}
+
+func TestMarshalsRegisteredTypes(t *testing.T) {
+
+	in := struct {
+		Listeners []testPlugin `kdl:"listener"`
+		Extra     []testPlugin
+	}{
+		Listeners: []testPlugin{
+			&testHTTPListener{Port: 80},
+			testGRPCListener{Port: 9000},
+		},
+		Extra: []testPlugin{
+			testGRPCListener{Port: 6379},
+		},
+	}
+
+	var b bytes.Buffer
+	e := NewEncoder(&b)
+	e.UseRegistry(newTestRegistry())
+	assert.NoError(t, e.Encode(in))
+	assert.Equal(t, `(http)listener port=80
+(grpc)listener port=9000 reflect=false
+(grpc)extra port=6379 reflect=false
+`, b.String())
+
+	var out = in
+	out.Listeners = nil
+	out.Extra = nil
+	d := NewDecoder(&b)
+	d.UseRegistry(newTestRegistry())
+	assert.NoError(t, d.DecodeDocument(&out))
+	assert.Equal(t, in, out)
+}
@@ -0,0 +1,348 @@
+package kdl
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Go types associated with type hints reserved by the KDL specification.
+var (
+	typeTime     = reflect.TypeOf(time.Time{})
+	typeDuration = reflect.TypeOf(time.Duration(0))
+	typeURL      = reflect.TypeOf(url.URL{})
+	typeAddr     = reflect.TypeOf(netip.Addr{})
+)
+
+// isReservedType checks if values of this type are written as strings annotated with a reserved type hint.
+func isReservedType(t reflect.Type) bool {
+	switch t {
+	case typeTime, typeDuration, typeURL, typeAddr:
+		return true
+	}
+	return isUUIDType(t)
+}
+
+// isUUIDType checks if the type is a [16]byte array, which is written as a (uuid).
+// Arrays with their own text (un)marshaling, such as hashes, are written the way they choose.
+func isUUIDType(t reflect.Type) bool {
+	if t.Kind() != reflect.Array || t.Len() != 16 || t.Elem().Kind() != reflect.Uint8 {
+		return false
+	}
+	pt := reflect.PointerTo(t)
+	return !pt.Implements(typeTextMarshaler) && !pt.Implements(typeTextUnmarshaler)
+}
+
+// reservedToKDLValue converts a value of a reserved type into a string annotated with its hint.
+// Returns false if the value is not of a reserved type.
+func reservedToKDLValue(v reflect.Value) (Value, bool) {
+
+	// Pointers to some of these types implement encoding.TextMarshaler
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	t := v.Type()
+	switch {
+	case t == typeTime:
+		s := formatTime(v.Interface().(time.Time), "date-time")
+		return NewStringValue(s, Hint("date-time")), true
+	case t == typeDuration:
+		s := formatISODuration(time.Duration(v.Int()))
+		return NewStringValue(s, Hint("duration")), true
+	case t == typeURL:
+		u := v.Interface().(url.URL)
+		return NewStringValue(u.String(), Hint("url")), true
+	case t == typeAddr:
+		a := v.Interface().(netip.Addr)
+		switch {
+		case a.Is4():
+			return NewStringValue(a.String(), Hint("ipv4")), true
+		case a.Is6():
+			return NewStringValue(a.String(), Hint("ipv6")), true
+		default:
+			// An unset address
+			return NewNullValue(NoHint()), true
+		}
+	case isUUIDType(t):
+		var b [16]byte
+		reflect.Copy(reflect.ValueOf(b[:]), v)
+		return NewStringValue(formatUUID(b), Hint("uuid")), true
+	}
+
+	return newInvalidValue(), false
+}
+
+// rehint annotates a Value converted from v with another hint.
+// Times are written again in the layout that hint is read with.
+func rehint(val Value, v reflect.Value, hint TypeHint) Value {
+
+	val.TypeHint = hint
+
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	if v.Type() == typeTime && val.Type == TypeString {
+		name, _ := hint.Get()
+		val.RawValue = formatTime(v.Interface().(time.Time), name)
+	}
+
+	return val
+}
+
+var errBadReservedValue = fmt.Errorf("%w string (not valid for its type)", ErrCannotUnmarshal)
+
+// kdlValueToReserved stores a string in a value of a reserved type,
+// parsing it according to its type hint.
+// Returns false if the value is not of a reserved type.
+func kdlValueToReserved(val Value, v reflect.Value) (bool, error) {
+
+	t := v.Type()
+	if !isReservedType(t) {
+		return false, nil
+	}
+
+	// An unset address is written as null
+	if t == typeAddr && (val.Type == TypeNull || (val.Type == TypeString && len(val.StringValue()) == 0)) {
+		v.SetZero()
+		return true, nil
+	}
+
+	// Durations can still be read from nanoseconds
+	if val.Type != TypeString {
+		if t == typeDuration {
+			return false, nil
+		}
+		return true, errCannotUnmarshalValue(val, t)
+	}
+
+	s := val.StringValue()
+	hint, _ := val.TypeHint.Get()
+
+	var err error
+	switch {
+	case t == typeTime:
+		var tm time.Time
+		tm, err = parseTime(s, hint)
+		if err == nil {
+			v.Set(reflect.ValueOf(tm))
+		}
+	case t == typeDuration:
+		var d time.Duration
+		d, err = parseDuration(s)
+		if err == nil {
+			v.SetInt(int64(d))
+		}
+	case t == typeURL:
+		var u *url.URL
+		u, err = url.Parse(s)
+		if err == nil {
+			v.Set(reflect.ValueOf(*u))
+		}
+	case t == typeAddr:
+		var a netip.Addr
+		a, err = parseAddr(s, hint)
+		if err == nil {
+			v.Set(reflect.ValueOf(a))
+		}
+	default:
+		var b [16]byte
+		b, err = parseUUID(s)
+		if err == nil {
+			reflect.Copy(v, reflect.ValueOf(b[:]))
+		}
+	}
+
+	if err != nil {
+		return true, fmt.Errorf("%w: %q into %s: %w", errBadReservedValue, s, t, err)
+	}
+	return true, nil
+}
+
+// formatTime writes a time in the layout parseTime reads for the hint.
+func formatTime(t time.Time, hint Identifier) string {
+	switch hint {
+	case "date":
+		return t.Format(time.DateOnly)
+	case "time":
+		return t.Format("15:04:05.999999999Z07:00")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// parseTime reads a (date-time), a (date) or a (time).
+// Strings without any of these hints are read as a (date-time).
+func parseTime(s string, hint Identifier) (time.Time, error) {
+	switch hint {
+	case "date":
+		return time.Parse(time.DateOnly, s)
+	case "time":
+		if t, err := time.Parse("15:04:05.999999999Z07:00", s); err == nil {
+			return t, nil
+		}
+		return time.Parse("15:04:05.999999999", s)
+	default:
+		return time.Parse(time.RFC3339Nano, s)
+	}
+}
+
+var errAddrFamily = errors.New("wrong IP address family")
+
+// parseAddr reads an IP address, checking that it matches an (ipv4) or an (ipv6) hint.
+func parseAddr(s string, hint Identifier) (netip.Addr, error) {
+
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return a, err
+	}
+
+	if (hint == "ipv4" && !a.Is4()) || (hint == "ipv6" && !a.Is6()) {
+		return netip.Addr{}, errAddrFamily
+	}
+
+	return a, nil
+}
+
+func formatUUID(b [16]byte) string {
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
+
+var errBadUUID = errors.New("expected 32 hexadecimal digits, optionally separated by hyphens")
+
+// parseUUID reads a UUID in its canonical form, or without the hyphens.
+func parseUUID(s string) ([16]byte, error) {
+
+	var b [16]byte
+	if len(s) == 36 && s[8] == '-' && s[13] == '-' && s[18] == '-' && s[23] == '-' {
+		s = s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+	}
+
+	if len(s) != 32 {
+		return b, errBadUUID
+	}
+
+	if _, err := hex.Decode(b[:], []byte(s)); err != nil {
+		return b, errBadUUID
+	}
+
+	return b, nil
+}
+
+// formatISODuration writes a duration in the ISO 8601 format, eg. PT1H30M.
+func formatISODuration(d time.Duration) string {
+
+	if d == 0 {
+		return "PT0S"
+	}
+
+	var b strings.Builder
+	u := uint64(d)
+	if d < 0 {
+		b.WriteByte('-')
+		u = -u
+	}
+	b.WriteString("PT")
+
+	if h := u / uint64(time.Hour); h > 0 {
+		b.WriteString(strconv.FormatUint(h, 10))
+		b.WriteByte('H')
+	}
+	if m := u / uint64(time.Minute) % 60; m > 0 {
+		b.WriteString(strconv.FormatUint(m, 10))
+		b.WriteByte('M')
+	}
+	if ns := u % uint64(time.Minute); ns > 0 {
+		secs := strconv.FormatUint(ns/uint64(time.Second), 10)
+		frac := strings.TrimRight(fmt.Sprintf("%09d", ns%uint64(time.Second)), "0")
+		b.WriteString(secs)
+		if len(frac) > 0 {
+			b.WriteByte('.')
+			b.WriteString(frac)
+		}
+		b.WriteByte('S')
+	}
+
+	return b.String()
+}
+
+var errBadDuration = errors.New("expected an ISO 8601 duration with days, hours, minutes or seconds")
+
+// parseDuration reads a duration in the ISO 8601 format (eg. P1DT12H),
+// or in the format used by time.ParseDuration (eg. 36h).
+// Years and months are not supported, as their length varies.
+func parseDuration(s string) (time.Duration, error) {
+
+	rest, neg := strings.CutPrefix(s, "-")
+	if !neg {
+		rest, _ = strings.CutPrefix(rest, "+")
+	}
+
+	rest, ok := strings.CutPrefix(rest, "P")
+	if !ok {
+		return time.ParseDuration(s)
+	}
+
+	if len(rest) == 0 {
+		return 0, errBadDuration
+	}
+
+	units := map[byte]time.Duration{'W': 7 * 24 * time.Hour, 'D': 24 * time.Hour}
+	var total float64
+	for len(rest) > 0 {
+
+		if rest[0] == 'T' {
+			if len(rest) == 1 {
+				return 0, errBadDuration
+			}
+			units = map[byte]time.Duration{'H': time.Hour, 'M': time.Minute, 'S': time.Second}
+			rest = rest[1:]
+			continue
+		}
+
+		i := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' && r != ',' })
+		if i <= 0 {
+			return 0, errBadDuration
+		}
+
+		unit, ok := units[rest[i]]
+		if !ok {
+			return 0, errBadDuration
+		}
+
+		n, err := strconv.ParseFloat(strings.Replace(rest[:i], ",", ".", 1), 64)
+		if err != nil {
+			return 0, errBadDuration
+		}
+
+		total += n * float64(unit)
+		delete(units, rest[i])
+		rest = rest[i+1:]
+	}
+
+	if total > math.MaxInt64 {
+		return 0, errBadDuration
+	}
+
+	if neg {
+		total = -total
+	}
+	return time.Duration(math.Round(total)), nil
+}
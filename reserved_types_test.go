@@ -0,0 +1,177 @@
+package kdl
+
+import (
+	"encoding/hex"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarshalsReservedTypes(t *testing.T) {
+
+	type deployment struct {
+		Started  time.Time
+		Timeout  time.Duration
+		Endpoint *url.URL
+		Address  netip.Addr
+		Mirror   netip.Addr
+		ID       [16]byte
+	}
+
+	endpoint, _ := url.Parse("https://example.com/api?v=2")
+	in := struct {
+		Deployment deployment
+	}{
+		Deployment: deployment{
+			Started:  time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC),
+			Timeout:  90*time.Minute + 1500*time.Millisecond,
+			Endpoint: endpoint,
+			Address:  netip.MustParseAddr("192.168.0.1"),
+			Mirror:   netip.MustParseAddr("2001:db8::1"),
+			ID:       [16]byte{0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00},
+		},
+	}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `deployment address=(ipv4)"192.168.0.1" endpoint=(url)"https://example.com/api?v=2" id=(uuid)"123e4567-e89b-12d3-a456-426614174000" mirror=(ipv6)"2001:db8::1" started=(date-time)"2024-03-01T12:30:00.0000005Z" timeout=(duration)"PT1H30M1.5S"
+`, string(data))
+
+	var out = in
+	out.Deployment = deployment{}
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+}
+
+func TestUnmarshalsReservedTypes(t *testing.T) {
+
+	var cfg struct {
+		Day     time.Time
+		Alarm   time.Time
+		Backoff time.Duration
+		Retry   time.Duration
+		Window  time.Duration
+		ID      [16]byte
+	}
+
+	assert.NoError(t, Unmarshal([]byte(`
+		day (date)"2024-02-29"
+		alarm (time)"07:30:00"
+		backoff (duration)"P1DT2H0.5S"
+		retry (duration)"250ms"
+		window 1000
+		id (uuid)"123e4567e89b12d3a456426614174000"
+	`), &cfg))
+
+	assert.Equal(t, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), cfg.Day)
+	assert.Equal(t, 7*time.Hour+30*time.Minute, cfg.Alarm.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 26*time.Hour+500*time.Millisecond, cfg.Backoff)
+	assert.Equal(t, 250*time.Millisecond, cfg.Retry)
+	assert.Equal(t, time.Microsecond, cfg.Window)
+	assert.Equal(t, byte(0x12), cfg.ID[0])
+	assert.Equal(t, byte(0x00), cfg.ID[15])
+}
+
+func TestReportsBadReservedValues(t *testing.T) {
+
+	var cfg struct {
+		Started time.Time
+		Timeout time.Duration
+		Address netip.Addr
+		ID      [16]byte
+		Link    *url.URL
+	}
+
+	for _, doc := range []string{
+		"\nstarted (date-time)\"yesterday\"",
+		"\nstarted (date)\"2024-13-01\"",
+		"\ntimeout (duration)\"P1Y\"",
+		"\ntimeout (duration)\"soon\"",
+		"\naddress (ipv4)\"::1\"",
+		"\naddress (ipv6)\"10.0.0.1\"",
+		"\nid (uuid)\"not-a-uuid\"",
+		"\nlink (url)\"http://[::1\"",
+		"\nstarted 5",
+	} {
+		err := Unmarshal([]byte(doc), &cfg)
+		var posErr *ErrWithPosition
+		if assert.ErrorAs(t, err, &posErr, doc) {
+			assert.Equal(t, 2, posErr.Line, doc)
+		}
+		assert.ErrorIs(t, err, ErrCannotUnmarshal, doc)
+	}
+}
+
+func TestRoundTripsUnsetAddress(t *testing.T) {
+
+	type server struct {
+		Address netip.Addr
+	}
+
+	in := struct{ Server server }{}
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, "server address=null\n", string(data))
+
+	out := struct{ Server server }{Server: server{Address: netip.MustParseAddr("10.0.0.1")}}
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+
+	assert.NoError(t, Unmarshal([]byte(`server address=""`), &out))
+	assert.Equal(t, in, out)
+}
+
+// testHash has its own text form, so it is not written as a (uuid).
+type testHash [16]byte
+
+func (h testHash) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h[:])), nil
+}
+
+func (h *testHash) UnmarshalText(text []byte) error {
+	_, err := hex.Decode(h[:], text)
+	return err
+}
+
+func TestMarshalsArrayTextMarshaler(t *testing.T) {
+
+	in := struct{ Hash testHash }{Hash: testHash{0xab, 15: 0xcd}}
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, "hash \"ab0000000000000000000000000000cd\"\n", string(data))
+
+	var out struct{ Hash testHash }
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+}
+
+func TestRoundTripsTimesWithHints(t *testing.T) {
+
+	type schedule struct {
+		Day   time.Time  `kdl:",hint=date"`
+		Alarm *time.Time `kdl:",hint=time"`
+	}
+
+	alarm := time.Date(0, 1, 1, 7, 30, 15, 0, time.UTC)
+	in := struct {
+		Schedule schedule
+	}{
+		Schedule: schedule{
+			Day:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+			Alarm: &alarm,
+		},
+	}
+
+	data, err := Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `schedule alarm=(time)"07:30:15Z" day=(date)"2020-01-02"
+`, string(data))
+
+	var out = in
+	out.Schedule = schedule{}
+	assert.NoError(t, Unmarshal(data, &out))
+	assert.Equal(t, in, out)
+}
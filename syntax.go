@@ -15,6 +15,14 @@ func isKeyword(s string) bool {
 	return slices.Contains(keywords[:], s)
 }
 
+// keywordsV2 are the strings that KDL 2.0.0 does not allow as bare identifiers,
+// since they would be confused with keywords written without their '#'.
+var keywordsV2 = [...]string{"true", "false", "null", "inf", "-inf", "nan"}
+
+func isKeywordV2(s string) bool {
+	return slices.Contains(keywordsV2[:], s)
+}
+
 // charsSlashDash represents a sequence of bytes
 // that tells the parser to discard the immediately following
 // node, argument or property.
@@ -97,6 +105,19 @@ func isAllowedBareIdentifier(s string) bool {
 	return !isKeyword(s) && patternBareIdentifier.MatchString(s)
 }
 
+// isAllowedBareIdentifierV2 checks if the string can be written as a bare identifier in KDL 2.0.0.
+func isAllowedBareIdentifierV2(s string) bool {
+	if s == "" || isKeywordV2(s) || startsLikeNumberV2(s) {
+		return false
+	}
+	for _, ch := range s {
+		if isWhitespace(ch) || isNewLine(ch) || !isRuneAllowedInBareIdentifierV2(ch) {
+			return false
+		}
+	}
+	return true
+}
+
 var asciiAllowedInBareIdent = [128]byte{
 	// 1  2  3  4  5  6  7  8  9  A  B  C  D  E  F
 	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 0x00 - 0x0F
@@ -116,11 +137,56 @@ func isRuneAllowedInBareIdentifier(ch rune) bool {
 	return ch <= 0x10ffff
 }
 
-// isAllowedInitialCharacter checks if a bare identifier is allowed to start with this rune.
-func isAllowedInitialCharacter(ch rune) bool {
-	return isRuneAllowedInBareIdentifier(ch) && !unicode.IsDigit(ch)
+var asciiAllowedInBareIdentV2 = [128]byte{
+	// 1  2  3  4  5  6  7  8  9  A  B  C  D  E  F
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 0x00 - 0x0F
+	0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // 0x10 - 0x1F
+	1, 1, 0, 0, 1, 1, 1, 1, 0, 0, 1, 1, 1, 1, 1, 0, // 0x20 - 0x2F
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 1, 0, 1, 1, // 0x30 - 0x3F
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, // 0x40 - 0x4F
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 0, 0, 1, 1, // 0x50 - 0x5F
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, // 0x60 - 0x6F
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 0, 1, 0, 1, 0, // 0x70 - 0x7F
+}
+
+// isRuneAllowedInBareIdentifierV2 checks if the rune can be a part of a bare identifier in KDL 2.0.0,
+// which allows '<', '>' and ',', but not '#', text direction controls nor a byte order mark.
+func isRuneAllowedInBareIdentifierV2(ch rune) bool {
+	if ch < 0x80 {
+		return asciiAllowedInBareIdentV2[byte(ch)] > 0
+	}
+	switch {
+	case ch == 0xfeff:
+		return false
+	case ch >= 0x200e && ch <= 0x200f, ch >= 0x202a && ch <= 0x202e, ch >= 0x2066 && ch <= 0x2069:
+		return false
+	case ch >= 0xd800 && ch <= 0xdfff:
+		return false
+	}
+	return ch <= 0x10ffff
+}
+
+// startsLikeNumberV2 checks if a string would be confused with a number in KDL 2.0.0,
+// ie. if it starts with a digit, optionally preceded by a sign and/or a dot.
+func startsLikeNumberV2(s string) bool {
+	if startsWithDigit(s) {
+		return true
+	}
+	if len(s) > 0 && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	if len(s) < 2 || s[0] != '.' {
+		return false
+	}
+	return startsWithDigit(s[1:])
 }
 
 func isValidValueTerminator(ch rune) bool {
 	return ch == ';' || ch == '}' || isWhitespace(ch) || isNewLine(ch)
 }
+
+// isValidValueTerminatorV2 also accepts the start of a children block,
+// which KDL 2.0.0 allows right after the last value of a node.
+func isValidValueTerminatorV2(ch rune) bool {
+	return ch == '{' || isValidValueTerminator(ch)
+}
@@ -0,0 +1,394 @@
+package kdl
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNoSuchNode is returned when editing a SyntaxTree at a path that does not lead to a node.
+var ErrNoSuchNode = errors.New("no node at this path")
+
+// SyntaxTree is a parsed document that keeps its source text,
+// so that it can be edited without losing comments, whitespace or the spelling of values.
+// Only the text of the edited parts changes; the rest of the document is kept byte-for-byte.
+//
+// Nodes are addressed by paths: the index of a top-level node,
+// followed by the index of a child at each level below it, eg. []int{2, 0}
+// is the first child of the third top-level node. Slashdashed nodes are not counted.
+type SyntaxTree struct {
+	src     []byte
+	version Version
+	doc     Document
+	spans   []nodeSpan
+}
+
+// ParseSyntaxTree parses a document from b, keeping its source text.
+func ParseSyntaxTree(b []byte) (*SyntaxTree, error) {
+	return ParseOptions{}.ParseSyntaxTree(b)
+}
+
+// ParseSyntaxTree parses a document from b, keeping its source text.
+func (o ParseOptions) ParseSyntaxTree(b []byte) (*SyntaxTree, error) {
+	t := &SyntaxTree{version: o.Version}
+	if err := t.reset(bytes.Clone(b)); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// reset parses src, replacing both the source text and the model of the tree.
+// If src is not valid, the tree is left as it was.
+func (t *SyntaxTree) reset(src []byte) error {
+
+	r := wrapReader(bufio.NewReader(bytes.NewReader(src)))
+	r.version = t.version
+	r.keepNotations = true
+
+	spans := make([]nodeSpan, 0, 8)
+	nodes, err := readNodesSpans(&r, &spans)
+	if err != nil {
+		return addErrPosInfo(err, &r)
+	}
+
+	t.src = src
+	t.version = r.version
+	t.doc = Document{Nodes: nodes}
+	attachDocumentComments(&t.doc, r.takeComments())
+	t.spans = spans
+	return nil
+}
+
+// Document returns the model of the tree.
+// It must not be modified; edit the tree with its methods instead.
+func (t *SyntaxTree) Document() Document {
+	return t.doc
+}
+
+// Bytes returns the source text of the tree, including all edits.
+func (t *SyntaxTree) Bytes() []byte {
+	return bytes.Clone(t.src)
+}
+
+// String returns the source text of the tree, including all edits.
+func (t *SyntaxTree) String() string {
+	return string(t.src)
+}
+
+// WriteTo writes the source text of the tree, including all edits, to w.
+func (t *SyntaxTree) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(t.src)
+	return int64(n), err
+}
+
+// SetProp sets a property of the node at the path.
+// If the node already has the property, only the text of its value is replaced.
+// Otherwise, the property is added after the last argument or property of the node.
+func (t *SyntaxTree) SetProp(path []int, key Identifier, v Value) error {
+
+	span, err := t.find(path)
+	if err != nil {
+		return err
+	}
+
+	value, err := t.render(func(w *writer) error { return writeValue(w, &v) })
+	if err != nil {
+		return err
+	}
+
+	if r, ok := span.propValues[key]; ok {
+		return t.splice(r.start, r.end, value)
+	}
+
+	ident, err := t.render(func(w *writer) error { return writeIdentifier(w, key) })
+	if err != nil {
+		return err
+	}
+
+	return t.splice(span.headEnd, span.headEnd, " "+ident+"="+value)
+}
+
+var errBadChildIndex = fmt.Errorf("%w (child index out of range)", ErrNoSuchNode)
+
+// InsertChild inserts a node as the index-th child of the node at the path,
+// or as the index-th top-level node, if the path is empty.
+// The new node is indented like its siblings.
+func (t *SyntaxTree) InsertChild(path []int, index int, n Node) error {
+
+	siblings := t.spans
+	var parent *nodeSpan
+	if len(path) > 0 {
+		var err error
+		parent, err = t.find(path)
+		if err != nil {
+			return err
+		}
+		siblings = parent.children
+	}
+
+	if index < 0 || index > len(siblings) {
+		return errBadChildIndex
+	}
+
+	nl := t.newLine()
+
+	// Before a sibling: on its own line, above its comments, if the sibling has one
+	if index < len(siblings) {
+		at := siblings[index].start
+		if indent, ok := t.indentAt(at); ok {
+			text, err := t.renderNode(&n, indent)
+			if err != nil {
+				return err
+			}
+			start := lineStart(t.src, at)
+			if siblings[index].commentsStart >= 0 {
+				start = siblings[index].commentsStart
+			}
+			return t.splice(start, start, indent+text+nl)
+		}
+		text, err := t.renderNode(&n, "")
+		if err != nil {
+			return err
+		}
+		return t.splice(at, at, text+"; ")
+	}
+
+	parentIndent := ""
+	if parent != nil {
+		parentIndent, _ = t.indentAt(parent.start)
+	}
+
+	indent := parentIndent
+	if len(siblings) > 0 {
+		indent, _ = t.indentAt(siblings[0].start)
+	} else if parent != nil {
+		indent += t.indentUnit(parentIndent)
+	}
+
+	text, err := t.renderNode(&n, indent)
+	if err != nil {
+		return err
+	}
+
+	// At the end of the document
+	if parent == nil {
+		end := len(t.src)
+		if end > 0 && !isNewLine(rune(t.src[end-1])) {
+			text = nl + indent + text
+		} else {
+			text = indent + text
+		}
+		return t.splice(end, end, text+nl)
+	}
+
+	// In a new children block
+	if parent.childrenOpen < 0 {
+		return t.splice(parent.headEnd, parent.headEnd, " {"+nl+indent+text+nl+parentIndent+"}")
+	}
+
+	// Before the closing bracket, on its own line
+	at := parent.childrenClose
+	if _, ok := t.indentAt(at); ok {
+		start := lineStart(t.src, at)
+		return t.splice(start, start, indent+text+nl)
+	}
+	return t.splice(at, at, nl+indent+text+nl+parentIndent)
+}
+
+// RemoveNode removes the node at the path, along with its children.
+// If the node has a line of its own, the whole line is removed, together with the comments above it.
+func (t *SyntaxTree) RemoveNode(path []int) error {
+
+	span, err := t.find(path)
+	if err != nil {
+		return err
+	}
+
+	start := span.start
+	end, endsLine := skipToLineEnd(t.src, span.end)
+
+	if !endsLine {
+		return t.splice(start, end, "")
+	}
+
+	if _, ok := t.indentAt(start); ok {
+		start = lineStart(t.src, start)
+		if span.commentsStart >= 0 {
+			start = lineStart(t.src, span.commentsStart)
+		}
+		return t.splice(start, end, "")
+	}
+
+	// Something precedes the node on its line, and still needs the line break
+	for start > 0 && (t.src[start-1] == ' ' || t.src[start-1] == '\t') {
+		start--
+	}
+	return t.splice(start, end, string(lineBreakBefore(t.src, end)))
+}
+
+// skipToLineEnd moves the offset past blanks and the line break that follows them.
+// Returns true if the line ends there, either with a line break or with the end of the source.
+func skipToLineEnd(src []byte, offset int) (int, bool) {
+
+	if offset > 0 && isNewLine(rune(src[offset-1])) {
+		return offset, true
+	}
+
+	for offset < len(src) && (src[offset] == ' ' || src[offset] == '\t') {
+		offset++
+	}
+
+	switch {
+	case offset == len(src):
+		return offset, true
+	case src[offset] == '\r' && offset+1 < len(src) && src[offset+1] == '\n':
+		return offset + 2, true
+	case isNewLine(rune(src[offset])):
+		return offset + 1, true
+	default:
+		return offset, false
+	}
+}
+
+// lineBreakBefore returns the line break that ends just before the offset, if there is one.
+func lineBreakBefore(src []byte, offset int) []byte {
+	switch {
+	case offset > 1 && src[offset-2] == '\r' && src[offset-1] == '\n':
+		return src[offset-2 : offset]
+	case offset > 0 && isNewLine(rune(src[offset-1])):
+		return src[offset-1 : offset]
+	default:
+		return nil
+	}
+}
+
+// find returns the span of the node at the path.
+func (t *SyntaxTree) find(path []int) (*nodeSpan, error) {
+
+	if len(path) == 0 {
+		return nil, ErrNoSuchNode
+	}
+
+	spans := t.spans
+	var span *nodeSpan
+	for _, i := range path {
+		if i < 0 || i >= len(spans) {
+			return nil, ErrNoSuchNode
+		}
+		span = &spans[i]
+		spans = span.children
+	}
+
+	return span, nil
+}
+
+// splice replaces the source text between start and end, then parses the tree again.
+func (t *SyntaxTree) splice(start, end int, text string) error {
+
+	src := make([]byte, 0, len(t.src)-(end-start)+len(text))
+	src = append(src, t.src[:start]...)
+	src = append(src, text...)
+	src = append(src, t.src[end:]...)
+
+	return t.reset(src)
+}
+
+// render writes a part of a document in the version of the tree.
+func (t *SyntaxTree) render(write func(w *writer) error) (string, error) {
+
+	var buf bytes.Buffer
+	w := writer{writer: bufio.NewWriter(&buf), version: t.version}
+	if err := write(&w); err != nil {
+		return "", err
+	}
+	if err := w.writer.Flush(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// renderNode writes a node, with all lines after the first one indented,
+// and its children indented the way the document does it.
+func (t *SyntaxTree) renderNode(n *Node, indent string) (string, error) {
+
+	unit := t.indentUnit(indent)
+	text, err := t.render(func(w *writer) error {
+		w.indent = unit
+		return writeNode(w, n)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(text, "\n", t.newLine()+indent), nil
+}
+
+// newLine returns the line break used by the document.
+func (t *SyntaxTree) newLine() string {
+	if i := bytes.IndexByte(t.src, '\n'); i > 0 && t.src[i-1] == '\r' {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// indentAt returns the whitespace between the start of the line and the offset.
+// Returns false if there is anything else there.
+func (t *SyntaxTree) indentAt(offset int) (string, bool) {
+	indent := t.src[lineStart(t.src, offset):offset]
+	if !isWhitespaceOnly(string(indent)) {
+		return "", false
+	}
+	return string(indent), true
+}
+
+// lineStart returns the offset of the start of the line the offset is in.
+func lineStart(src []byte, offset int) int {
+	for offset > 0 && src[offset-1] != '\n' && src[offset-1] != '\r' {
+		offset--
+	}
+	return offset
+}
+
+// indentUnit works out what one level of indentation looks like in the document,
+// from the first child indented more than its parent.
+// If no node is indented, it is guessed from the indentation of the parent.
+func (t *SyntaxTree) indentUnit(parentIndent string) string {
+	if unit, ok := t.findIndentUnit(t.spans); ok {
+		return unit
+	}
+	return defaultIndentUnit(parentIndent)
+}
+
+// findIndentUnit looks for a child indented more than its parent among the nodes and their descendants.
+func (t *SyntaxTree) findIndentUnit(spans []nodeSpan) (string, bool) {
+
+	for i := range spans {
+		parentIndent, ok := t.indentAt(spans[i].start)
+		if !ok {
+			continue
+		}
+		for j := range spans[i].children {
+			indent, ok := t.indentAt(spans[i].children[j].start)
+			if ok && len(indent) > len(parentIndent) && strings.HasPrefix(indent, parentIndent) {
+				return indent[len(parentIndent):], true
+			}
+		}
+		if unit, ok := t.findIndentUnit(spans[i].children); ok {
+			return unit, true
+		}
+	}
+
+	return "", false
+}
+
+// defaultIndentUnit guesses what one level of indentation looks like, given the indentation of a parent.
+func defaultIndentUnit(parentIndent string) string {
+	if strings.HasPrefix(parentIndent, "\t") {
+		return "\t"
+	}
+	return "    "
+}
@@ -0,0 +1,189 @@
+package kdl
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const inputSyntaxTree = `// Deployment config
+service "api" version="1.4.2" replicas=0x10 {
+	/* ports */
+	port 8_080 // http
+	port 9090;
+}
+
+/- disabled "yes"
+worker threads=4
+`
+
+func TestSyntaxTreeKeepsSource(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte(inputSyntaxTree))
+	assert.NoError(t, err)
+	assert.Equal(t, inputSyntaxTree, tree.String())
+
+	doc := tree.Document()
+	assert.Len(t, doc.Nodes, 2)
+	assert.Equal(t, int64(16), doc.Nodes[0].Props["replicas"].IntegerValue().Int64())
+}
+
+func TestSyntaxTreeSetsProp(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte(inputSyntaxTree))
+	assert.NoError(t, err)
+
+	assert.NoError(t, tree.SetProp([]int{0}, "version", NewStringValue("1.5.0", NoHint())))
+	assert.NoError(t, tree.SetProp([]int{0, 1}, "proto", NewStringValue("grpc", NoHint())))
+	assert.NoError(t, tree.SetProp([]int{1}, "threads", NewIntegerValue(big.NewInt(8), Hint("u8"))))
+
+	assert.Equal(t, `// Deployment config
+service "api" version="1.5.0" replicas=0x10 {
+	/* ports */
+	port 8_080 // http
+	port 9090 proto="grpc";
+}
+
+/- disabled "yes"
+worker threads=(u8)8
+`, tree.String())
+	assert.Equal(t, "1.5.0", tree.Document().Nodes[0].Props["version"].StringValue())
+
+	assert.ErrorIs(t, tree.SetProp([]int{0, 2}, "proto", NewNullValue(NoHint())), ErrNoSuchNode)
+}
+
+func TestSyntaxTreeInsertsChild(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte(inputSyntaxTree))
+	assert.NoError(t, err)
+
+	port := NewNode("port")
+	port.AddArg(443)
+	assert.NoError(t, tree.InsertChild([]int{0}, 0, port))
+
+	env := NewNode("env")
+	env.AddArg("prod")
+	assert.NoError(t, tree.InsertChild([]int{0}, 3, env))
+
+	limits := NewNode("limits")
+	limits.AddChild(NewNode("cpu"))
+	assert.NoError(t, tree.InsertChild([]int{1}, 0, limits))
+
+	assert.NoError(t, tree.InsertChild(nil, 2, NewNode("cache")))
+
+	assert.Equal(t, `// Deployment config
+service "api" version="1.4.2" replicas=0x10 {
+	port 443
+	/* ports */
+	port 8_080 // http
+	port 9090;
+	env "prod"
+}
+
+/- disabled "yes"
+worker threads=4 {
+	limits {
+		cpu
+	}
+}
+cache
+`, tree.String())
+
+	assert.ErrorIs(t, tree.InsertChild([]int{0}, 5, env), ErrNoSuchNode)
+}
+
+func TestSyntaxTreeInsertsChildInline(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte("a { b; c }\nd {}"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, tree.InsertChild([]int{0}, 1, NewNode("x")))
+	assert.NoError(t, tree.InsertChild([]int{1}, 0, NewNode("y")))
+	assert.NoError(t, tree.InsertChild(nil, 2, NewNode("z")))
+
+	assert.Equal(t, "a { b; x; c }\nd {\n    y\n}\nz\n", tree.String())
+}
+
+func TestSyntaxTreeInsertsChildAboveComments(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte("// header\n\n// top\npkg \"a\"\n// second\n/* really */\npkg \"b\"\n"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, tree.InsertChild(nil, 0, NewNode("x")))
+	assert.NoError(t, tree.InsertChild(nil, 2, NewNode("y")))
+	assert.Equal(t, "// header\n\nx\n// top\npkg \"a\"\ny\n// second\n/* really */\npkg \"b\"\n", tree.String())
+
+	doc := tree.Document()
+	assert.Equal(t, []string{"// top"}, doc.Nodes[1].Comments.Before)
+	assert.Equal(t, []string{"// second", "/* really */"}, doc.Nodes[3].Comments.Before)
+}
+
+func TestSyntaxTreeRemovesNode(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte(inputSyntaxTree))
+	assert.NoError(t, err)
+
+	assert.NoError(t, tree.RemoveNode([]int{0, 0}))
+	assert.NoError(t, tree.RemoveNode([]int{1}))
+
+	assert.Equal(t, `// Deployment config
+service "api" version="1.4.2" replicas=0x10 {
+	port 9090;
+}
+
+/- disabled "yes"
+`, tree.String())
+
+	tree, err = ParseSyntaxTree([]byte("a { b; c }\r\nd\r\ne"))
+	assert.NoError(t, err)
+	assert.NoError(t, tree.RemoveNode([]int{0, 1}))
+	assert.NoError(t, tree.RemoveNode([]int{1}))
+	assert.Equal(t, "a { b; }\r\ne", tree.String())
+}
+
+func TestSyntaxTreeRemovesNodeWithComments(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte("a\n\n// about b\n/* more */\nb 1\nc\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, tree.RemoveNode([]int{1}))
+	assert.Equal(t, "a\n\nc\n", tree.String())
+}
+
+func TestSyntaxTreeRemovesNodeKeepingLineBreak(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte("a { b; };\nnext 2\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, tree.RemoveNode([]int{0}))
+	assert.Equal(t, "next 2\n", tree.String())
+
+	tree, err = ParseSyntaxTree([]byte("a 1; b 2\nc 3\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, tree.RemoveNode([]int{1}))
+	assert.Equal(t, "a 1;\nc 3\n", tree.String())
+
+	tree, err = ParseSyntaxTree([]byte("a 1; b 2;\r\nc 3"))
+	assert.NoError(t, err)
+	assert.NoError(t, tree.RemoveNode([]int{1}))
+	assert.Equal(t, "a 1;\r\nc 3", tree.String())
+}
+
+func TestSyntaxTreeInsertsChildWithDocumentIndent(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte("a {\n\tb\n}\nc\n"))
+	assert.NoError(t, err)
+
+	child := NewNode("d")
+	child.AddChild(NewNode("e"))
+	assert.NoError(t, tree.InsertChild([]int{1}, 0, child))
+	assert.Equal(t, "a {\n\tb\n}\nc {\n\td {\n\t\te\n\t}\n}\n", tree.String())
+}
+
+func TestSyntaxTreeEditsV2(t *testing.T) {
+
+	tree, err := ParseSyntaxTree([]byte("/- kdl-version 2\nnode enabled=#false #\"raw\"#\n"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, tree.SetProp([]int{0}, "enabled", NewBoolValue(true, NoHint())))
+	assert.Equal(t, "/- kdl-version 2\nnode enabled=#true #\"raw\"#\n", tree.String())
+}
@@ -1,5 +1,1040 @@
 package kdl
 
-func unmarshal(data []byte, dst interface{}) error {
+import (
+	"bytes"
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// Unmarshaler is implemented by types that can read themselves from a KDL node.
+type Unmarshaler interface {
+	UnmarshalKDL(n *Node) error
+}
+
+// ValueUnmarshaler is implemented by types that can read themselves from a single KDL Value,
+// such as an argument or a property.
+type ValueUnmarshaler interface {
+	UnmarshalKDLValue(v Value) error
+}
+
+var (
+	typeUnmarshaler      = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	typeValueUnmarshaler = reflect.TypeOf((*ValueUnmarshaler)(nil)).Elem()
+	typeTextUnmarshaler  = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// asUnmarshalInterface returns a pointer to the value as T, if it implements the interface.
+func asUnmarshalInterface[T any](v reflect.Value) (T, bool) {
+	var zero T
+	iface := reflect.TypeOf((*T)(nil)).Elem()
+	if v.Kind() != reflect.Pointer && v.CanAddr() && reflect.PointerTo(v.Type()).Implements(iface) {
+		return v.Addr().Interface().(T), true
+	}
+	return zero, false
+}
+
+var (
+	// ErrCannotUnmarshal is a base error for when
+	// a part of a KDL document cannot be stored in the provided Go value.
+	ErrCannotUnmarshal = errors.New("cannot unmarshal")
+
+	errUnmarshalTarget      = errors.New("unmarshal target must be a non-nil pointer")
+	errCannotUnmarshalType  = fmt.Errorf("%w children (only structs and maps are supported)", ErrCannotUnmarshal)
+	errUnmarshalBadMapKey   = fmt.Errorf("%w into a map (only maps with string or encoding.TextUnmarshaler keys are supported)", ErrCannotUnmarshal)
+	errExpectedSingleArg    = fmt.Errorf("%w node (expected exactly one argument)", ErrCannotUnmarshal)
+	errExpectedSingleNode   = fmt.Errorf("%w children into an Unmarshaler (expected exactly one node)", ErrCannotUnmarshal)
+	errUnmarshalNumOverflow = fmt.Errorf("%w number (value out of range)", ErrCannotUnmarshal)
+	errArrayTooShort        = fmt.Errorf("%w into an array (too many elements)", ErrCannotUnmarshal)
+	errBadStringifiedValue  = fmt.Errorf("%w string (expected a number or a boolean)", ErrCannotUnmarshal)
+	errBadMetadataTarget    = fmt.Errorf("%w node metadata (only strings can hold names and type hints, and only maps can hold properties)", ErrCannotUnmarshal)
+)
+
+// Unmarshal parses a KDL document and stores the result in the value pointed to by v.
+//
+// The target must be a pointer to a struct, a map with string keys, a slice or an empty interface.
+// Struct fields and map values holding slices of structs or maps
+// collect every node of the same name.
+// Empty interfaces receive plain Go values in the shape described by UntypedOptions,
+// except that a node stored in an empty interface field
+// becomes just its argument if it has nothing but a single argument.
+// Interfaces with methods can only be filled by a Decoder using a Registry.
+// Top-level nodes are matched with struct fields by name,
+// using the same `kdl` struct tags as Marshal.
+// Nodes that do not match any field are ignored;
+// use a Decoder with DisallowUnknownFields to reject them instead.
+// Values implementing Unmarshaler read their nodes with their UnmarshalKDL method instead,
+// except for the one pointed to by v, whose fields always receive the top-level nodes.
+func Unmarshal(data []byte, v any) error {
+	return NewDecoder(bytes.NewReader(data)).DecodeDocument(v)
+}
+
+var (
+	// ErrUnknownField is a base error for when
+	// a strict Decoder comes across a node or a property that does not match any field.
+	ErrUnknownField = errors.New("unknown field")
+	// ErrMissingField is a base error for when
+	// a node, an argument or a property marked as required is not present.
+	ErrMissingField = errors.New("missing required field")
+)
+
+// UnknownFieldsError lists every node and property that did not match any field
+// of the target during strict decoding.
+type UnknownFieldsError struct {
+	// Errs holds one error per unknown node or property.
+	// If the position of the field is known, the error is an *ErrWithPosition.
+	Errs []error
+}
+
+// Error formats an error message.
+func (e *UnknownFieldsError) Error() string {
+	return joinErrorMessages(e.Errs)
+}
+
+// Unwrap returns the errors of every unknown field.
+func (e *UnknownFieldsError) Unwrap() []error {
+	return e.Errs
+}
+
+// MissingFieldsError lists every node, argument and property
+// that was marked as required, but was not present in the document.
+type MissingFieldsError struct {
+	// Errs holds one error per missing field.
+	// If the position of the node that should contain the field is known,
+	// the error is an *ErrWithPosition.
+	Errs []error
+}
+
+// Error formats an error message.
+func (e *MissingFieldsError) Error() string {
+	return joinErrorMessages(e.Errs)
+}
+
+// Unwrap returns the errors of every missing field.
+func (e *MissingFieldsError) Unwrap() []error {
+	return e.Errs
+}
+
+func joinErrorMessages(errs []error) string {
+	var b strings.Builder
+	for i, err := range errs {
+		if i > 0 {
+			b.WriteString("; ")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// pathElem is a node on the path from the top of the document to the node being decoded.
+type pathElem struct {
+	name Identifier
+	span *nodeSpan
+}
+
+// unmarshalContext holds the options and the state of a single decoding operation.
+type unmarshalContext struct {
+	disallowUnknownFields bool
+	registry              *Registry
+	fields                *fieldCache
+	unknownFields         []error
+	missingFields         []error
+	path                  []pathElem
+}
+
+func (c *unmarshalContext) pushPath(name Identifier, span *nodeSpan) {
+	c.path = append(c.path, pathElem{name: name, span: span})
+}
+
+func (c *unmarshalContext) popPath() {
+	c.path = c.path[:len(c.path)-1]
+}
+
+// currentPath returns a dotted path of node names, from the top of the document
+// to the node currently being decoded.
+func (c *unmarshalContext) currentPath() string {
+	var b strings.Builder
+	for i, p := range c.path {
+		if i > 0 {
+			b.WriteByte('.')
+		}
+		b.WriteString(string(p.name))
+	}
+	return b.String()
+}
+
+// currentPosition returns the position of the node currently being decoded, if known.
+func (c *unmarshalContext) currentPosition() *position {
+	if len(c.path) == 0 {
+		return nil
+	}
+	if span := c.path[len(c.path)-1].span; span != nil {
+		return &span.pos
+	}
 	return nil
 }
+
+// pathTo returns a dotted path to a child of the node currently being decoded.
+func (c *unmarshalContext) pathTo(name Identifier) string {
+	if len(c.path) == 0 {
+		return string(name)
+	}
+	return c.currentPath() + "." + string(name)
+}
+
+// unknownNode records a node that did not match any field.
+func (c *unmarshalContext) unknownNode(name Identifier, span *nodeSpan) {
+	if !c.disallowUnknownFields {
+		return
+	}
+	err := fmt.Errorf("%w: node %q", ErrUnknownField, c.pathTo(name))
+	if span != nil {
+		err = withPosition(err, &span.pos)
+	}
+	c.unknownFields = append(c.unknownFields, err)
+}
+
+// unknownProp records a property that did not match any field.
+func (c *unmarshalContext) unknownProp(key Identifier, span *nodeSpan) {
+	if !c.disallowUnknownFields {
+		return
+	}
+	err := fmt.Errorf("%w: property %q of node %q", ErrUnknownField, key, c.currentPath())
+	c.unknownFields = append(c.unknownFields, withPosition(err, span.propPosition(key)))
+}
+
+// missingField records a required field that is not present in the node currently being decoded.
+func (c *unmarshalContext) missingField(f *fieldInfo, argIndex int) {
+
+	var err error
+	switch f.purpose {
+	case purposeArgument:
+		err = fmt.Errorf("%w: argument %d of node %q", ErrMissingField, argIndex, c.currentPath())
+	case purposeProperty:
+		err = fmt.Errorf("%w: property %q of node %q", ErrMissingField, f.name, c.currentPath())
+	default:
+		c.missingNode(Identifier(f.name))
+		return
+	}
+
+	c.missingFields = append(c.missingFields, withPosition(err, c.currentPosition()))
+}
+
+// missingNode records a required child node that is not present in the node currently being decoded.
+func (c *unmarshalContext) missingNode(name Identifier) {
+	err := fmt.Errorf("%w: node %q", ErrMissingField, c.pathTo(name))
+	c.missingFields = append(c.missingFields, withPosition(err, c.currentPosition()))
+}
+
+// finish returns an error if the decoding was not successful.
+func (c *unmarshalContext) finish(err error) error {
+
+	if err != nil {
+		return err
+	}
+
+	var unknownErr, missingErr error
+	if len(c.unknownFields) > 0 {
+		unknownErr = &UnknownFieldsError{Errs: c.unknownFields}
+	}
+	if len(c.missingFields) > 0 {
+		missingErr = &MissingFieldsError{Errs: c.missingFields}
+	}
+
+	if unknownErr != nil && missingErr != nil {
+		return errors.Join(unknownErr, missingErr)
+	} else if unknownErr != nil {
+		return unknownErr
+	}
+	return missingErr
+}
+
+// withPosition wraps an error with its position in the document, if it is known.
+func withPosition(err error, pos *position) error {
+	if err == nil || pos == nil {
+		return err
+	}
+	return &ErrWithPosition{Err: err, Line: pos.line, Column: pos.column}
+}
+
+// spanAt returns the i-th span from the list, if known.
+func spanAt(spans []nodeSpan, i int) *nodeSpan {
+	if i >= len(spans) {
+		return nil
+	}
+	return &spans[i]
+}
+
+// childSpans returns the spans of the node's children, if known.
+func childSpans(span *nodeSpan) []nodeSpan {
+	if span == nil {
+		return nil
+	}
+	return span.children
+}
+
+// childrenToValue stores a list of nodes in a struct or a map.
+func childrenToValue(c *unmarshalContext, nodes []Node, spans []nodeSpan, v reflect.Value) error {
+
+	if u, ok := asUnmarshalInterface[Unmarshaler](v); ok {
+		if len(nodes) != 1 {
+			return errExpectedSingleNode
+		}
+		span := spanAt(spans, 0)
+		if err := u.UnmarshalKDL(&nodes[0]); err != nil {
+			if span != nil {
+				return withPosition(err, &span.pos)
+			}
+			return err
+		}
+		return nil
+	}
+
+	return childrenToFields(c, nodes, spans, v)
+}
+
+// childrenToFields stores a list of nodes in the fields, entries or elements of v,
+// without checking if v is an Unmarshaler itself.
+func childrenToFields(c *unmarshalContext, nodes []Node, spans []nodeSpan, v reflect.Value) error {
+
+	if v.Type() == typeNodes {
+		v.Set(reflect.ValueOf(slices.Clone(nodes)))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return childrenToValue(c, nodes, spans, v.Elem())
+	case reflect.Struct:
+		return childrenToStruct(c, nodes, spans, v)
+	case reflect.Map:
+		return childrenToMap(c, nodes, spans, v)
+	case reflect.Slice, reflect.Array:
+		return fillSequence(v, len(nodes), func(i int, elem reflect.Value) error {
+			if err := nodeToValue(c, &nodes[i], spanAt(spans, i), elem); err != nil {
+				return fmt.Errorf("node %q: %w", nodes[i].Name, err)
+			}
+			return nil
+		})
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(untypedNodes(nodes, &UntypedOptions{})))
+			return nil
+		}
+	}
+
+	return errCannotUnmarshalType
+}
+
+// fillSequence replaces the contents of a slice or an array with count elements,
+// each of them decoded by fn.
+func fillSequence(v reflect.Value, count int, fn func(i int, elem reflect.Value) error) error {
+
+	if v.Kind() == reflect.Array {
+		if count > v.Len() {
+			return errArrayTooShort
+		}
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if i >= count {
+				elem.SetZero()
+				continue
+			}
+			if err := fn(i, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if count == 0 {
+		v.SetZero()
+		return nil
+	}
+
+	seq := reflect.MakeSlice(v.Type(), count, count)
+	for i := 0; i < count; i++ {
+		if err := fn(i, seq.Index(i)); err != nil {
+			return err
+		}
+	}
+	v.Set(seq)
+	return nil
+}
+
+// repeatedNodesToValue stores every listed node as an element of a slice or an array.
+func repeatedNodesToValue(c *unmarshalContext, nodes []Node, spans []nodeSpan, indices []int, v reflect.Value) error {
+	return fillSequence(v, len(indices), func(i int, elem reflect.Value) error {
+		n := &nodes[indices[i]]
+		if err := nodeToValue(c, n, spanAt(spans, indices[i]), elem); err != nil {
+			return fmt.Errorf("node %q: %w", n.Name, err)
+		}
+		return nil
+	})
+}
+
+func childrenToStruct(c *unmarshalContext, nodes []Node, spans []nodeSpan, s reflect.Value) error {
+
+	fields := c.fields.structFields(s.Type())
+	byName := make(map[Identifier]*fieldInfo, len(fields))
+	var rest *fieldInfo // A []Node field collecting nodes that do not match any other field
+	for i := range fields {
+		f := &fields[i]
+		switch {
+		case f.purpose == purposeName || f.purpose == purposeTypeHint || f.purpose == purposeProps:
+			continue
+		case f.purpose == purposeChildren && s.Type().FieldByIndex(f.index).Type == typeNodes:
+			rest = f
+			continue
+		}
+		byName[Identifier(f.name)] = f
+	}
+
+	if err := applyDefaults(fields, s); err != nil {
+		return err
+	}
+
+	// Repeated nodes are collected first, so that all of them can be stored at once
+	repeated := make(map[*fieldInfo][]int)
+	present := make(map[*fieldInfo]bool, len(fields))
+	var restNodes []Node
+
+	for i := range nodes {
+
+		n := &nodes[i]
+		span := spanAt(spans, i)
+		f, ok := byName[n.Name]
+		if !ok && rest != nil {
+			restNodes = append(restNodes, *n)
+			continue
+		}
+		if !ok {
+			c.unknownNode(n.Name, span)
+			continue
+		}
+
+		present[f] = true
+
+		v := fieldByIndex(s, f.index)
+		if !v.IsValid() {
+			continue
+		}
+
+		if isRepeatedType(v.Type()) {
+			repeated[f] = append(repeated[f], i)
+			continue
+		}
+
+		if err := nodeToField(c, n, span, v, f); err != nil {
+			return fmt.Errorf("node %q: %w", n.Name, err)
+		}
+	}
+
+	for i := range fields {
+		f := &fields[i]
+		indices, ok := repeated[f]
+		if !ok {
+			continue
+		}
+		if err := repeatedNodesToValue(c, nodes, spans, indices, fieldByIndex(s, f.index)); err != nil {
+			return err
+		}
+	}
+
+	if rest != nil && len(restNodes) > 0 {
+		present[rest] = true
+		if v := fieldByIndex(s, rest.index); v.IsValid() {
+			v.Set(reflect.ValueOf(restNodes))
+		}
+	}
+
+	for i := range fields {
+		if f := &fields[i]; f.required && !present[f] {
+			c.missingNode(Identifier(f.name))
+		}
+	}
+
+	return nil
+}
+
+// applyDefaults assigns default values from the "default=" tag option to struct fields.
+func applyDefaults(fields []fieldInfo, s reflect.Value) error {
+	for i := range fields {
+
+		f := &fields[i]
+		if f.defaultValue == nil {
+			continue
+		}
+
+		v := fieldByIndex(s, f.index)
+		if !v.IsValid() {
+			continue
+		}
+
+		// The cached value is shared, so every field gets its own copy
+		if err := kdlValueToField(f.defaultValue.clone(), v, f); err != nil {
+			return fmt.Errorf("default value of field %q: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+func childrenToMap(c *unmarshalContext, nodes []Node, spans []nodeSpan, m reflect.Value) error {
+
+	t := m.Type()
+	kt := t.Key()
+	if kt.Kind() != reflect.String && !reflect.PointerTo(kt).Implements(typeTextUnmarshaler) {
+		return errUnmarshalBadMapKey
+	}
+
+	if m.IsNil() {
+		m.Set(reflect.MakeMapWithSize(t, len(nodes)))
+	}
+
+	repeated := isRepeatedType(t.Elem())
+
+	for i := range nodes {
+
+		n := &nodes[i]
+		span := spanAt(spans, i)
+
+		k, err := nameToMapKey(n.Name, kt)
+		if err != nil {
+			if span != nil {
+				err = withPosition(err, &span.pos)
+			}
+			return fmt.Errorf("node %q: %w", n.Name, err)
+		}
+
+		var v reflect.Value
+		if repeated {
+			// Every node with the same name becomes an element of the same slice
+			v = reflect.New(t.Elem().Elem()).Elem()
+		} else {
+			v = reflect.New(t.Elem()).Elem()
+		}
+
+		if err := nodeToValue(c, n, span, v); err != nil {
+			return fmt.Errorf("node %q: %w", n.Name, err)
+		}
+
+		if repeated {
+			v = reflect.Append(m.MapIndex(k), v)
+		}
+		m.SetMapIndex(k, v)
+	}
+
+	return nil
+}
+
+// nameToMapKey converts the name of a node to a map key.
+func nameToMapKey(name Identifier, kt reflect.Type) (reflect.Value, error) {
+
+	k := reflect.New(kt).Elem()
+	if u, ok := asUnmarshalInterface[encoding.TextUnmarshaler](k); ok {
+		if err := u.UnmarshalText([]byte(name)); err != nil {
+			return k, err
+		}
+		return k, nil
+	}
+
+	k.SetString(string(name))
+	return k, nil
+}
+
+// isSimpleNode checks if the node carries nothing but a single argument.
+func isSimpleNode(n *Node) bool {
+	return len(n.Args) == 1 && len(n.Props) == 0 && len(n.Children) == 0
+}
+
+// isNullNode checks if the node carries nothing but a single null argument.
+func isNullNode(n *Node) bool {
+	return isSimpleNode(n) && n.Args[0].Type == TypeNull
+}
+
+// nodeToField stores a single node in a struct field, respecting the field's options.
+func nodeToField(c *unmarshalContext, n *Node, span *nodeSpan, v reflect.Value, f *fieldInfo) error {
+
+	if !f.asString {
+		return nodeToValue(c, n, span, v)
+	}
+
+	var pos *position
+	if span != nil {
+		pos = &span.pos
+	}
+
+	if len(n.Args) != 1 {
+		return withPosition(errExpectedSingleArg, pos)
+	}
+
+	return withPosition(kdlValueToField(n.Args[0], v, f), pos)
+}
+
+// kdlValueToField stores a single KDL Value in a struct field, respecting the field's options.
+func kdlValueToField(val Value, v reflect.Value, f *fieldInfo) error {
+
+	if f.asString && val.Type == TypeString {
+		parsed, err := parseStringifiedValue(val, indirectType(v.Type()))
+		if err != nil {
+			return err
+		}
+		val = parsed
+	}
+
+	return kdlValueToValue(val, v)
+}
+
+// parseStringifiedValue reads a number or a boolean written as a string with the "string" tag option.
+func parseStringifiedValue(val Value, t reflect.Type) (Value, error) {
+
+	s := val.StringValue()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return val, errBadStringifiedValue
+		}
+		return NewBoolValue(b, val.TypeHint), nil
+	case reflect.Float32, reflect.Float64:
+		f, _, err := big.ParseFloat(s, 10, 53, big.ToNearestEven)
+		if err != nil {
+			return val, errBadStringifiedValue
+		}
+		return NewFloatValue(f, val.TypeHint), nil
+	default:
+		i, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return val, errBadStringifiedValue
+		}
+		return NewIntegerValue(i, val.TypeHint), nil
+	}
+}
+
+// argsToSequence stores arguments as elements of a slice or an array.
+func argsToSequence(args []Value, v reflect.Value) error {
+	return fillSequence(v, len(args), func(i int, elem reflect.Value) error {
+		if err := kdlValueToValue(args[i], elem); err != nil {
+			return fmt.Errorf("argument %d: %w", i, err)
+		}
+		return nil
+	})
+}
+
+// nodeToValue stores a single node in a Go value.
+func nodeToValue(c *unmarshalContext, n *Node, span *nodeSpan, v reflect.Value) error {
+
+	var pos *position
+	if span != nil {
+		pos = &span.pos
+	}
+
+	if u, ok := asUnmarshalInterface[Unmarshaler](v); ok {
+		if err := u.UnmarshalKDL(n); err != nil {
+			return withPosition(err, pos)
+		}
+		return nil
+	}
+
+	if !isScalarType(v.Type()) {
+		switch v.Kind() {
+		case reflect.Pointer:
+			if isNullNode(n) {
+				v.SetZero()
+				return nil
+			}
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			return nodeToValue(c, n, span, v.Elem())
+		case reflect.Struct:
+			c.pushPath(n.Name, span)
+			defer c.popPath()
+			return nodeToStruct(c, n, span, v)
+		case reflect.Map:
+			return childrenToMap(c, n.Children, childSpans(span), v)
+		case reflect.Slice, reflect.Array:
+			if isCompositeType(v.Type().Elem()) {
+				return childrenToValue(c, n.Children, childSpans(span), v)
+			}
+			return withPosition(argsToSequence(n.Args, v), pos)
+		case reflect.Interface:
+			if t, ok := c.registry.lookup(n, v.Type()); ok {
+				target := reflect.New(t).Elem()
+				if err := nodeToValue(c, n, span, target); err != nil {
+					return err
+				}
+				v.Set(target)
+				return nil
+			}
+			if v.NumMethod() > 0 {
+				return withPosition(fmt.Errorf("%w: %q into %s", errNoRegisteredType, n.Name, v.Type()), pos)
+			}
+			if !isSimpleNode(n) {
+				v.Set(reflect.ValueOf(untypedNode(n, &UntypedOptions{})))
+				return nil
+			}
+		}
+	}
+
+	if len(n.Args) != 1 {
+		return withPosition(errExpectedSingleArg, pos)
+	}
+
+	return withPosition(kdlValueToValue(n.Args[0], v), pos)
+}
+
+func nodeToStruct(c *unmarshalContext, n *Node, span *nodeSpan, s reflect.Value) error {
+
+	var pos *position
+	if span != nil {
+		pos = &span.pos
+	}
+
+	fields := c.fields.structFields(s.Type())
+
+	// Nodes claimed by child fields are not passed to the children field
+	childNames := make(map[Identifier]struct{})
+	propNames := make(map[Identifier]struct{})
+	hasChildrenField := false
+	hasPropsField := false
+	for _, f := range fields {
+		switch f.purpose {
+		case purposeChild:
+			childNames[Identifier(f.name)] = struct{}{}
+		case purposeProperty:
+			propNames[Identifier(f.name)] = struct{}{}
+		case purposeChildren:
+			hasChildrenField = true
+		case purposeProps:
+			hasPropsField = true
+		}
+	}
+
+	if c.disallowUnknownFields {
+		if !hasPropsField {
+			for _, key := range sortedPropKeys(n) {
+				if _, ok := propNames[key]; !ok {
+					c.unknownProp(key, span)
+				}
+			}
+		}
+		if !hasChildrenField {
+			for i := range n.Children {
+				child := &n.Children[i]
+				if _, ok := childNames[child.Name]; !ok {
+					c.unknownNode(child.Name, span.childSpan(i))
+				}
+			}
+		}
+	}
+
+	if err := applyDefaults(fields, s); err != nil {
+		return withPosition(err, pos)
+	}
+
+	argIndex := 0
+	for i := range fields {
+
+		f := &fields[i]
+		v := fieldByIndex(s, f.index)
+		if !v.IsValid() {
+			continue
+		}
+
+		present := false
+		missingArgIndex := argIndex
+
+		switch f.purpose {
+		case purposeArgument:
+			if argIndex < len(n.Args) {
+				present = true
+				if err := kdlValueToField(n.Args[argIndex], v, f); err != nil {
+					return fmt.Errorf("argument %d: %w", argIndex, withPosition(err, pos))
+				}
+			}
+			argIndex++
+		case purposeArguments:
+			var rest []Value
+			if argIndex < len(n.Args) {
+				rest = n.Args[argIndex:]
+				present = true
+			}
+			if err := argsToSequence(rest, v); err != nil {
+				return withPosition(err, pos)
+			}
+			argIndex = len(n.Args)
+		case purposeProperty:
+			key := Identifier(f.name)
+			if n.HasProp(key) {
+				present = true
+				if err := kdlValueToField(n.Props[key], v, f); err != nil {
+					return fmt.Errorf("property %q: %w", f.name, withPosition(err, span.propPosition(key)))
+				}
+			}
+		case purposeProps:
+			for _, key := range sortedPropKeys(n) {
+				if _, ok := propNames[key]; ok {
+					continue
+				}
+				present = true
+				if err := propToMap(key, n.Props[key], v); err != nil {
+					return fmt.Errorf("property %q: %w", key, withPosition(err, span.propPosition(key)))
+				}
+			}
+		case purposeName:
+			if v.Kind() != reflect.String {
+				return withPosition(errBadMetadataTarget, pos)
+			}
+			present = true
+			v.SetString(string(n.Name))
+		case purposeTypeHint:
+			hint, ok := n.TypeHint.Get()
+			present = ok
+			if v.Type() == typeTypeHint {
+				v.Set(reflect.ValueOf(n.TypeHint))
+			} else if v.Kind() == reflect.String {
+				v.SetString(string(hint))
+			} else {
+				return withPosition(errBadMetadataTarget, pos)
+			}
+		case purposeChild:
+			if isRepeatedType(v.Type()) {
+				indices := make([]int, 0, 4)
+				for i := range n.Children {
+					if n.Children[i].Name == Identifier(f.name) {
+						indices = append(indices, i)
+					}
+				}
+				if len(indices) > 0 {
+					present = true
+					if err := repeatedNodesToValue(c, n.Children, childSpans(span), indices, v); err != nil {
+						return err
+					}
+				}
+				break
+			}
+			for i := range n.Children {
+				child := &n.Children[i]
+				if child.Name != Identifier(f.name) {
+					continue
+				}
+				present = true
+				if err := nodeToField(c, child, span.childSpan(i), v, f); err != nil {
+					return fmt.Errorf("node %q: %w", child.Name, err)
+				}
+			}
+		case purposeChildren:
+			children := n.Children
+			spans := childSpans(span)
+			if len(childNames) > 0 {
+				children = make([]Node, 0, len(n.Children))
+				spans = make([]nodeSpan, 0, len(spans))
+				for i, child := range n.Children {
+					if _, ok := childNames[child.Name]; !ok {
+						children = append(children, child)
+						if cs := span.childSpan(i); cs != nil {
+							spans = append(spans, *cs)
+						}
+					}
+				}
+			}
+			present = len(children) > 0
+			if err := childrenToValue(c, children, spans, v); err != nil {
+				return err
+			}
+		}
+
+		if f.required && !present {
+			c.missingField(f, missingArgIndex)
+		}
+	}
+
+	return nil
+}
+
+// propToMap stores a property in a map, which collects properties not claimed by other fields.
+func propToMap(key Identifier, val Value, m reflect.Value) error {
+
+	t := m.Type()
+	if t.Kind() != reflect.Map {
+		return errBadMetadataTarget
+	}
+
+	k, err := nameToMapKey(key, t.Key())
+	if err != nil {
+		return err
+	}
+
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(t))
+	}
+
+	elem := reflect.New(t.Elem()).Elem()
+	if err := kdlValueToValue(val, elem); err != nil {
+		return err
+	}
+
+	m.SetMapIndex(k, elem)
+	return nil
+}
+
+// sortedPropKeys returns the property names of a node in a stable order.
+func sortedPropKeys(n *Node) []Identifier {
+	keys := maps.Keys(n.Props)
+	slices.Sort(keys)
+	return keys
+}
+
+// fieldByIndex returns a nested struct field, allocating embedded pointers along the way.
+// Returns an invalid reflect.Value if the field cannot be reached.
+func fieldByIndex(s reflect.Value, index []int) reflect.Value {
+	v := s
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// kdlValueToValue stores a single KDL Value in a Go value.
+func kdlValueToValue(val Value, v reflect.Value) error {
+
+	if err := checkNumericHint(val); err != nil {
+		return err
+	}
+
+	if u, ok := asUnmarshalInterface[ValueUnmarshaler](v); ok {
+		return u.UnmarshalKDLValue(val)
+	}
+
+	t := v.Type()
+	switch t {
+	case typeValue:
+		v.Set(reflect.ValueOf(val))
+		return nil
+	case typeBigInt:
+		if val.Type == TypeInteger {
+			v.Set(reflect.ValueOf(new(big.Int).Set(val.IntegerValue())))
+			return nil
+		}
+	case typeBigFloat:
+		switch val.Type {
+		case TypeFloat:
+			v.Set(reflect.ValueOf(new(big.Float).Copy(val.FloatValue())))
+			return nil
+		case TypeInteger:
+			v.Set(reflect.ValueOf(new(big.Float).SetInt(val.IntegerValue())))
+			return nil
+		}
+	}
+
+	if val.Type == TypeNull {
+		switch v.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			v.SetZero()
+			return nil
+		}
+	}
+
+	if ok, err := kdlValueToReserved(val, v); ok {
+		return err
+	}
+
+	if val.Type == TypeString {
+		if u, ok := asUnmarshalInterface[encoding.TextUnmarshaler](v); ok {
+			return u.UnmarshalText([]byte(val.StringValue()))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return kdlValueToValue(val, v.Elem())
+	case reflect.Interface:
+		if v.NumMethod() == 0 {
+			v.Set(reflect.ValueOf(untypedValue(val, &UntypedOptions{})))
+			return nil
+		}
+	case reflect.String:
+		if val.Type == TypeString {
+			v.SetString(val.StringValue())
+			return nil
+		}
+	case reflect.Bool:
+		if val.Type == TypeBool {
+			v.SetBool(val.BoolValue())
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if val.Type == TypeInteger {
+			i := val.IntegerValue()
+			if !i.IsInt64() || v.OverflowInt(i.Int64()) {
+				return errUnmarshalNumOverflow
+			}
+			v.SetInt(i.Int64())
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if val.Type == TypeInteger {
+			i := val.IntegerValue()
+			if !i.IsUint64() || v.OverflowUint(i.Uint64()) {
+				return errUnmarshalNumOverflow
+			}
+			v.SetUint(i.Uint64())
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		var exact *big.Float
+		switch val.Type {
+		case TypeNaN:
+			f = math.NaN()
+		case TypeFloat:
+			exact = val.FloatValue()
+		case TypeInteger:
+			exact = new(big.Float).SetInt(val.IntegerValue())
+		default:
+			return errCannotUnmarshalValue(val, t)
+		}
+		if exact != nil {
+			// A finite number too large for a float64 becomes an infinity
+			f, _ = exact.Float64()
+			if math.IsInf(f, 0) && !exact.IsInf() {
+				return errUnmarshalNumOverflow
+			}
+		}
+		if v.OverflowFloat(f) {
+			return errUnmarshalNumOverflow
+		}
+		v.SetFloat(f)
+		return nil
+	}
+
+	return errCannotUnmarshalValue(val, t)
+}
+
+func errCannotUnmarshalValue(val Value, t reflect.Type) error {
+	return fmt.Errorf("%w %s into Go value of type %s", ErrCannotUnmarshal, val.Type, t)
+}
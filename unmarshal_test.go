@@ -0,0 +1,252 @@
+package kdl
+
+import (
+	"bytes"
+	"math"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnmarshalsStruct(t *testing.T) {
+
+	type server struct {
+		Host string `kdl:",argument"`
+		Port uint16
+		Tags map[string]string `kdl:",children"`
+	}
+
+	var cfg struct {
+		Name    string
+		Server  server
+		Backup  *server `kdl:"backup-server"`
+		Retries *int
+		Ignored string `kdl:"-"`
+	}
+
+	err := Unmarshal([]byte(`
+		name "prod"
+		server "example.com" port=8080 {
+			env "production"
+		}
+		backup-server "backup.example.com" port=8081
+		retries null
+		ignored "foo"
+		unknown 1 2 3
+	`), &cfg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", cfg.Name)
+	assert.Equal(t, "example.com", cfg.Server.Host)
+	assert.EqualValues(t, 8080, cfg.Server.Port)
+	assert.Equal(t, map[string]string{"env": "production"}, cfg.Server.Tags)
+	if assert.NotNil(t, cfg.Backup) {
+		assert.Equal(t, "backup.example.com", cfg.Backup.Host)
+	}
+	assert.Nil(t, cfg.Retries)
+	assert.Empty(t, cfg.Ignored)
+}
+
+func TestUnmarshalsMap(t *testing.T) {
+	var m map[string]float64
+	err := Unmarshal([]byte("foo 1.5\nbar 2"), &m)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]float64{"foo": 1.5, "bar": 2}, m)
+}
+
+func TestUnmarshalReportsErrors(t *testing.T) {
+
+	var s struct {
+		Small int8
+	}
+
+	err := Unmarshal([]byte("small 300"), &s)
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+
+	err = Unmarshal([]byte(`small "foo"`), &s)
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+
+	err = Unmarshal([]byte("small 1 2"), &s)
+	assert.ErrorIs(t, err, ErrCannotUnmarshal)
+
+	err = Unmarshal([]byte("small 1"), s)
+	assert.Error(t, err)
+
+	err = Unmarshal([]byte("small }"), &s)
+	assert.ErrorIs(t, err, ErrInvalidSyntax)
+}
+
+func TestUnmarshalAppliesDefaults(t *testing.T) {
+
+	type server struct {
+		Host    string  `kdl:"host,default=localhost"`
+		Port    int     `kdl:"port,default=8080"`
+		Ratio   float64 `kdl:"ratio,default=0.5"`
+		Verbose *bool   `kdl:"verbose,default=true"`
+	}
+
+	var cfg struct {
+		Server  server
+		Workers uint `kdl:"workers,default=4"`
+	}
+
+	assert.NoError(t, Unmarshal([]byte(`server port=9090`), &cfg))
+	assert.Equal(t, "localhost", cfg.Server.Host)
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, 0.5, cfg.Server.Ratio)
+	if assert.NotNil(t, cfg.Server.Verbose) {
+		assert.True(t, *cfg.Server.Verbose)
+	}
+	assert.EqualValues(t, 4, cfg.Workers)
+}
+
+func TestUnmarshalReportsMissingFields(t *testing.T) {
+
+	type server struct {
+		Name string `kdl:",argument,required"`
+		Host string `kdl:"host,required"`
+		Port int    `kdl:"port,required"`
+	}
+
+	var cfg struct {
+		Server server `kdl:"server,required"`
+		Admin  string `kdl:"admin,required"`
+	}
+
+	err := Unmarshal([]byte("\nserver port=80\n"), &cfg)
+
+	var missingErr *MissingFieldsError
+	if assert.ErrorAs(t, err, &missingErr) && assert.Len(t, missingErr.Errs, 3) {
+		assert.Contains(t, missingErr.Errs[0].Error(), `argument 0 of node "server" [line 2, column 0]`)
+		assert.Contains(t, missingErr.Errs[1].Error(), `property "host" of node "server" [line 2, column 0]`)
+		assert.Contains(t, missingErr.Errs[2].Error(), `node "admin"`)
+	}
+	assert.ErrorIs(t, err, ErrMissingField)
+
+	assert.NoError(t, Unmarshal([]byte(`server "main" host="example.com" port=80; admin "root"`), &cfg))
+}
+
+func TestUnmarshalRejectsFloatOverflow(t *testing.T) {
+
+	var cfg struct {
+		Ratio float64
+	}
+
+	huge := "1" + strings.Repeat("0", 400)
+	assert.ErrorIs(t, Unmarshal([]byte("ratio "+huge), &cfg), errUnmarshalNumOverflow)
+	assert.ErrorIs(t, Unmarshal([]byte("ratio 1.0e400"), &cfg), errUnmarshalNumOverflow)
+
+	assert.NoError(t, Unmarshal([]byte("ratio 1"+strings.Repeat("0", 300)), &cfg))
+	assert.Equal(t, 1e300, cfg.Ratio)
+}
+
+func TestUnmarshalChecksNumericHints(t *testing.T) {
+
+	var cfg struct {
+		Port  int
+		Ratio float64
+		Any   any
+	}
+
+	assert.NoError(t, Unmarshal([]byte("port (u16)65535\nratio (f32)0.5\nany (i8)-128"), &cfg))
+	assert.Equal(t, 65535, cfg.Port)
+
+	for _, doc := range []string{
+		"\nport (u8)300",
+		"\nport (i8)-129",
+		"\nport (u32)-1",
+		"\nport (i64)1.5",
+		"\nratio (f32)1e39",
+		"\nany (u8)256",
+	} {
+		err := Unmarshal([]byte(doc), &cfg)
+		var posErr *ErrWithPosition
+		if assert.ErrorAs(t, err, &posErr, doc) {
+			assert.Equal(t, 2, posErr.Line, doc)
+		}
+		assert.ErrorIs(t, err, ErrCannotUnmarshal, doc)
+	}
+}
+
+func TestSizeHintsMatchInt(t *testing.T) {
+
+	assert.Equal(t, big.NewInt(math.MaxInt), intHintRanges["isize"].max)
+	assert.Equal(t, big.NewInt(math.MinInt), intHintRanges["isize"].min)
+	assert.Equal(t, new(big.Int).SetUint64(math.MaxUint), intHintRanges["usize"].max)
+}
+
+func TestUnmarshalsNodeMetadata(t *testing.T) {
+
+	type plugin struct {
+		Name  string           `kdl:",name"`
+		Kind  string           `kdl:",typehint"`
+		Hint  TypeHint         `kdl:",typehint"`
+		ID    int              `kdl:",argument"`
+		Rest  []Value          `kdl:",args"`
+		Port  int              `kdl:",property"`
+		Props map[string]Value `kdl:",props"`
+		Debug bool             `kdl:",child"`
+		Raw   []Node           `kdl:",children"`
+	}
+
+	var cfg struct {
+		Plugins []plugin `kdl:"plugin"`
+		Other   []Node   `kdl:",children"`
+	}
+
+	data := []byte(`(http)plugin 1 "a" 2 port=80 host="localhost" retries=3 {
+	debug true
+	route "/" { handler "index" }
+	route "/api"
+}
+plugin 2
+unknown 5
+`)
+	d := NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if !assert.NoError(t, d.DecodeDocument(&cfg)) || !assert.Len(t, cfg.Plugins, 2) {
+		return
+	}
+
+	p := cfg.Plugins[0]
+	assert.Equal(t, "plugin", p.Name)
+	assert.Equal(t, "http", p.Kind)
+	assert.Equal(t, Hint("http"), p.Hint)
+	assert.Equal(t, 1, p.ID)
+	assert.Equal(t, []Value{NewStringValue("a", NoHint()), NewIntegerValue(big.NewInt(2), NoHint())}, p.Rest)
+	assert.Equal(t, 80, p.Port)
+	assert.Equal(t, map[string]Value{
+		"host":    NewStringValue("localhost", NoHint()),
+		"retries": NewIntegerValue(big.NewInt(3), NoHint()),
+	}, p.Props)
+	assert.True(t, p.Debug)
+	if assert.Len(t, p.Raw, 2) {
+		assert.Equal(t, Identifier("route"), p.Raw[0].Name)
+		assert.Len(t, p.Raw[0].Children, 1)
+	}
+
+	assert.Equal(t, "", cfg.Plugins[1].Kind)
+	assert.True(t, cfg.Plugins[1].Hint.IsAbsent())
+
+	if assert.Len(t, cfg.Other, 1) {
+		assert.Equal(t, Identifier("unknown"), cfg.Other[0].Name)
+	}
+
+	// Metadata fields are written back
+	out, err := Marshal(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, `(http)plugin 1 "a" 2 host="localhost" port=80 retries=3 {
+    debug true
+    route "/" {
+        handler "index"
+    }
+    route "/api"
+}
+plugin 2 port=0 {
+    debug false
+}
+unknown 5
+`, string(out))
+}
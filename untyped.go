@@ -0,0 +1,126 @@
+package kdl
+
+import (
+	"math"
+	"math/big"
+)
+
+// UntypedOptions configures how KDL is converted into plain Go values.
+//
+// By default, every node becomes a map[string]any with the following keys:
+//
+//	"name"     string          name of the node
+//	"args"     []any           arguments of the node
+//	"props"    map[string]any  properties of the node
+//	"children" []any           children of the node, converted the same way
+//
+// and every Value becomes one of:
+//
+//	nil        for nulls
+//	bool       for booleans
+//	string     for strings
+//	int64      for integers, or *big.Int if the integer does not fit
+//	float64    for floating point numbers
+type UntypedOptions struct {
+	// OmitEmpty skips the "args", "props" and "children" keys
+	// if the node has no arguments, properties or children respectively.
+	OmitEmpty bool
+	// BigNumbers keeps all integers as *big.Int and all floats as *big.Float.
+	BigNumbers bool
+	// TypeHints adds a "type" key to the map of every node that has a type hint.
+	// Values with type hints become a map[string]any
+	// with the hint under the "type" key and the value itself under the "value" key.
+	TypeHints bool
+}
+
+// Untyped converts all nodes of the Document into plain Go values.
+// See UntypedOptions for the shape of the result.
+func (d *Document) Untyped(opts UntypedOptions) []any {
+	return untypedNodes(d.Nodes, &opts)
+}
+
+// Untyped converts the Node into plain Go values.
+// See UntypedOptions for the shape of the result.
+func (n *Node) Untyped(opts UntypedOptions) map[string]any {
+	return untypedNode(n, &opts)
+}
+
+// Untyped converts the Value into a plain Go value.
+// See UntypedOptions for the shape of the result.
+func (v Value) Untyped(opts UntypedOptions) any {
+	return untypedValue(v, &opts)
+}
+
+func untypedNodes(nodes []Node, opts *UntypedOptions) []any {
+	res := make([]any, len(nodes))
+	for i := range nodes {
+		res[i] = untypedNode(&nodes[i], opts)
+	}
+	return res
+}
+
+func untypedNode(n *Node, opts *UntypedOptions) map[string]any {
+
+	m := make(map[string]any, 5)
+	m["name"] = string(n.Name)
+
+	if opts.TypeHints {
+		if hint, ok := n.TypeHint.Get(); ok {
+			m["type"] = string(hint)
+		}
+	}
+
+	if len(n.Args) > 0 || !opts.OmitEmpty {
+		args := make([]any, len(n.Args))
+		for i, arg := range n.Args {
+			args[i] = untypedValue(arg, opts)
+		}
+		m["args"] = args
+	}
+
+	if len(n.Props) > 0 || !opts.OmitEmpty {
+		props := make(map[string]any, len(n.Props))
+		for k, prop := range n.Props {
+			props[string(k)] = untypedValue(prop, opts)
+		}
+		m["props"] = props
+	}
+
+	if len(n.Children) > 0 || !opts.OmitEmpty {
+		m["children"] = untypedNodes(n.Children, opts)
+	}
+
+	return m
+}
+
+func untypedValue(v Value, opts *UntypedOptions) any {
+
+	var res any
+	switch v.Type {
+	case TypeBool, TypeString:
+		res = v.RawValue
+	case TypeInteger:
+		i := v.IntegerValue()
+		if !opts.BigNumbers && i.IsInt64() {
+			res = i.Int64()
+		} else {
+			res = new(big.Int).Set(i)
+		}
+	case TypeNaN:
+		res = math.NaN()
+	case TypeFloat:
+		if !opts.BigNumbers {
+			res = v.float64Value()
+		} else {
+			res = new(big.Float).Copy(v.FloatValue())
+		}
+	}
+
+	if opts.TypeHints {
+		if hint, ok := v.TypeHint.Get(); ok {
+			return map[string]any{"type": string(hint), "value": res}
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,65 @@
+package kdl
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertsToUntyped(t *testing.T) {
+
+	doc, err := ParseString(`(srv)server "main" 1.5 port=8080 big=123456789012345678901234567890 {
+		tag null
+	}`)
+	assert.NoError(t, err)
+
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, []any{
+		map[string]any{
+			"name": "server",
+			"args": []any{"main", 1.5},
+			"props": map[string]any{
+				"port": int64(8080),
+				"big":  huge,
+			},
+			"children": []any{
+				map[string]any{
+					"name":     "tag",
+					"args":     []any{nil},
+					"props":    map[string]any{},
+					"children": []any{},
+				},
+			},
+		},
+	}, doc.Untyped(UntypedOptions{}))
+
+	assert.Equal(t, map[string]any{
+		"name": "tag",
+		"args": []any{nil},
+	}, doc.Nodes[0].Children[0].Untyped(UntypedOptions{OmitEmpty: true}))
+
+	assert.Equal(t, "srv", doc.Nodes[0].Untyped(UntypedOptions{TypeHints: true})["type"])
+}
+
+func TestConvertsHintedValueToUntyped(t *testing.T) {
+	v := NewIntegerValue(big.NewInt(3), Hint("u8"))
+	assert.Equal(t, int64(3), v.Untyped(UntypedOptions{}))
+	assert.Equal(t, map[string]any{"type": "u8", "value": big.NewInt(3)}, v.Untyped(UntypedOptions{TypeHints: true, BigNumbers: true}))
+}
+
+func TestUnmarshalsIntoUntyped(t *testing.T) {
+
+	var s struct {
+		Simple  any
+		Complex any
+	}
+
+	assert.NoError(t, Unmarshal([]byte("simple 42\ncomplex 1 2"), &s))
+	assert.Equal(t, int64(42), s.Simple)
+	assert.Equal(t, []any{int64(1), int64(2)}, s.Complex.(map[string]any)["args"])
+
+	var all any
+	assert.NoError(t, Unmarshal([]byte("foo; bar"), &all))
+	assert.Len(t, all, 2)
+}
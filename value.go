@@ -2,6 +2,7 @@ package kdl
 
 import (
 	"errors"
+	"math"
 	"math/big"
 	"reflect"
 )
@@ -17,8 +18,29 @@ const (
 	TypeString  // The described Value holds a string.
 	TypeInteger // The described Value holds an integer.
 	TypeFloat   // The described Value holds a floating point number.
+	TypeNaN     // The described Value holds a floating point NaN, which a *big.Float cannot represent.
 )
 
+// String returns a human-readable name of the type.
+func (t TypeTag) String() string {
+	switch t {
+	case TypeNull:
+		return "null"
+	case TypeBool:
+		return "boolean"
+	case TypeString:
+		return "string"
+	case TypeInteger:
+		return "integer"
+	case TypeFloat:
+		return "float"
+	case TypeNaN:
+		return "NaN"
+	default:
+		return "invalid"
+	}
+}
+
 var errInvalidTypeTag = errors.New("value has invalid type tag")
 
 // Value can be used either as an argument or a property to a Node.
@@ -26,6 +48,8 @@ type Value struct {
 	RawValue interface{}
 	TypeHint TypeHint
 	Type     TypeTag
+
+	notation *Notation // How the value was written in the document it was parsed from.
 }
 
 // NewNullValue constructs a Value that holds a null.
@@ -77,7 +101,19 @@ func NewFloatValue(v *big.Float, hint TypeHint) Value {
 	return Value{Type: TypeFloat, RawValue: v, TypeHint: hint}
 }
 
+// NewNaNValue constructs a Value that holds a floating point NaN.
+// Its type is TypeNaN, not TypeFloat, as a *big.Float cannot represent it.
+func NewNaNValue(hint TypeHint) Value {
+	return Value{Type: TypeNaN, RawValue: math.NaN(), TypeHint: hint}
+}
+
+// IsNaN checks if the Value holds a floating point NaN.
+func (v Value) IsNaN() bool {
+	return v.Type == TypeNaN
+}
+
 // FloatValue returns the inner float value or panics, if the Value is not a floating point number.
+// A NaN is not one of them, see TypeNaN.
 func (v Value) FloatValue() *big.Float {
 	if v.Type != TypeFloat {
 		panic("value is not a real number")
@@ -85,6 +121,26 @@ func (v Value) FloatValue() *big.Float {
 	return v.RawValue.(*big.Float)
 }
 
+// float64Value returns the inner float value as a float64, including a NaN.
+func (v Value) float64Value() float64 {
+	if v.IsNaN() {
+		return math.NaN()
+	}
+	f, _ := v.FloatValue().Float64()
+	return f
+}
+
+// clone returns a copy of the Value that does not share its number with the original.
+func (v Value) clone() Value {
+	switch raw := v.RawValue.(type) {
+	case *big.Int:
+		v.RawValue = new(big.Int).Set(raw)
+	case *big.Float:
+		v.RawValue = new(big.Float).Copy(raw)
+	}
+	return v
+}
+
 // newInvalidValue constructs a new Value that is in an invalid state.
 func newInvalidValue() Value {
 	return Value{Type: TypeInvalid}
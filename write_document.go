@@ -74,9 +74,49 @@ func writeProps(w *writer, n *Node) error {
 	return nil
 }
 
+// writeCommentLines writes comments, each on its own line, as they stand above a node.
+func writeCommentLines(w *writer, comments []string, indent string) error {
+
+	for _, c := range comments {
+		if c != "" {
+			if _, err := w.writer.WriteString(indent + formatComment(c, indent)); err != nil {
+				return err
+			}
+		}
+		if err := w.writer.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeTrailingComments writes comments on the same line as the end of a node.
+func writeTrailingComments(w *writer, comments []string, indent string) error {
+
+	for _, c := range comments {
+		if err := writeSpace(w); err != nil {
+			return err
+		}
+		if _, err := w.writer.WriteString(formatComment(c, indent)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func writeNode(w *writer, n *Node) error {
 
-	indent := strings.Repeat("    ", w.depth)
+	unit := w.indent
+	if len(unit) == 0 {
+		unit = "    "
+	}
+	indent := strings.Repeat(unit, w.depth)
+	if err := writeCommentLines(w, n.Comments.Before, indent); err != nil {
+		return err
+	}
+
 	if _, err := w.writer.WriteString(indent); err != nil {
 		return err
 	}
@@ -138,13 +178,25 @@ func writeNode(w *writer, n *Node) error {
 		}
 	}
 
-	return nil
+	return writeTrailingComments(w, n.Comments.After, indent)
 }
 
 func writeDocument(w *writer, d *Document) error {
 
 	nodes := d.Nodes
 
+	if len(d.Comments.Before) > 0 {
+		if err := writeCommentLines(w, d.Comments.Before, ""); err != nil {
+			return err
+		}
+		// The header is separated from the first node by a blank line
+		if len(nodes) > 0 {
+			if err := w.writer.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+	}
+
 	for i := range nodes {
 		node := &nodes[i]
 		if err := writeNode(w, node); err != nil {
@@ -157,12 +209,55 @@ func writeDocument(w *writer, d *Document) error {
 		}
 	}
 
+	if len(d.Comments.After) > 0 {
+		if len(nodes) > 0 {
+			if err := w.writer.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+		// Avoid a line break left over after the last comment
+		comments := d.Comments.After
+		if err := writeCommentLines(w, comments[:len(comments)-1], ""); err != nil {
+			return err
+		}
+		if last := comments[len(comments)-1]; last != "" {
+			if _, err := w.writer.WriteString(formatComment(last, "")); err != nil {
+				return err
+			}
+		}
+	}
+
 	return nil
 }
 
+// WriteOptions configures how documents are written.
+// The zero value writes KDL 1.0.0.
+type WriteOptions struct {
+	// Version of the specification to write the document in. Auto means V1.
+	Version Version
+	// VersionMarker makes the document start with a `/- kdl-version N` node,
+	// so that parsers can tell which version it is written in.
+	VersionMarker bool
+}
+
 // Write writes the Document to an io.Writer.
-func (d *Document) Write(w io.Writer) error {
-	bw := writer{writer: bufio.NewWriter(w)}
+func (o WriteOptions) Write(w io.Writer, d *Document) error {
+
+	bw := writer{writer: bufio.NewWriter(w), version: o.Version}
+	if bw.version == Auto {
+		bw.version = V1
+	}
+
+	if o.VersionMarker {
+		marker := "/- kdl-version 1\n"
+		if bw.version == V2 {
+			marker = "/- kdl-version 2\n"
+		}
+		if _, err := bw.writer.WriteString(marker); err != nil {
+			return err
+		}
+	}
+
 	if err := writeDocument(&bw, d); err != nil {
 		return err
 	}
@@ -173,8 +268,18 @@ func (d *Document) Write(w io.Writer) error {
 }
 
 // WriteString marshals the Document to a new string.
-func (d *Document) WriteString() (string, error) {
+func (o WriteOptions) WriteString(d *Document) (string, error) {
 	var buf bytes.Buffer
-	err := d.Write(&buf)
+	err := o.Write(&buf, d)
 	return buf.String(), err
 }
+
+// Write writes the Document to an io.Writer.
+func (d *Document) Write(w io.Writer) error {
+	return WriteOptions{}.Write(w, d)
+}
+
+// WriteString marshals the Document to a new string.
+func (d *Document) WriteString() (string, error) {
+	return WriteOptions{}.WriteString(d)
+}
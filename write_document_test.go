@@ -2,6 +2,7 @@ package kdl
 
 import (
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -40,3 +41,46 @@ func TestDocumentWritesCorrectly(t *testing.T) {
 "ghi jkl"
 `, s)
 }
+
+func TestDocumentWritesV2(t *testing.T) {
+
+	doc := Document{
+		Nodes: []Node{
+			{
+				Name:     "true",
+				TypeHint: Hint("my type"),
+				Args: []Value{
+					NewBoolValue(true, NoHint()),
+					NewNullValue(NoHint()),
+					NewFloatValue(new(big.Float).SetInf(true), NoHint()),
+					NewNaNValue(NoHint()),
+					NewStringValue(`C:\"kdl"#`, NoHint()),
+					NewStringValue("a\tb\u2028", NoHint()),
+				},
+				Props: map[Identifier]Value{
+					"<x>": NewStringValue("y", NoHint()),
+					".5":  NewIntegerValue(big.NewInt(5), NoHint()),
+				},
+			},
+		},
+	}
+
+	s, err := WriteOptions{Version: V2, VersionMarker: true}.WriteString(&doc)
+	assert.NoError(t, err)
+	assert.Equal(t, `/- kdl-version 2
+("my type")"true" #true #null #-inf #nan ##"C:\"kdl"#"## "a\tb\u{2028}" ".5"=5 <x>="y"
+`, s)
+
+	parsed, err := ParseString(s)
+	assert.NoError(t, err)
+	assert.Equal(t, `C:\"kdl"#`, parsed.Nodes[0].Args[4].StringValue())
+	assert.True(t, parsed.Nodes[0].Args[3].IsNaN())
+
+	_, err = doc.WriteString()
+	assert.ErrorIs(t, err, errNonFiniteV1)
+
+	doc.Nodes[0].Args = doc.Nodes[0].Args[:2]
+	s, err = doc.WriteString()
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(s, `("my type")"true" true null`))
+}
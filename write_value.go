@@ -1,6 +1,8 @@
 package kdl
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"strconv"
@@ -19,6 +21,10 @@ var unescapeReplacer = strings.NewReplacer(
 
 func writeString(w *writer, s string) error {
 
+	if w.version == V2 {
+		return writeStringV2(w, s)
+	}
+
 	if err := w.writer.WriteByte('"'); err != nil {
 		return err
 	}
@@ -28,7 +34,90 @@ func writeString(w *writer, s string) error {
 	return w.writer.WriteByte('"')
 }
 
+// writeStringV2 writes a string as defined by KDL 2.0.0.
+// Strings with backslashes are written as raw strings, if they do not need other escapes.
+func writeStringV2(w *writer, s string) error {
+
+	needsEscapes := strings.IndexFunc(s, func(r rune) bool {
+		_, escaped := escapesForWriteV2[r]
+		return r != '\\' && r != '"' && (escaped || isDisallowedLiteralV2(r))
+	}) >= 0
+
+	if strings.ContainsRune(s, '\\') && !needsEscapes {
+		return writeRawString(w, s)
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, r := range s {
+		if e, ok := escapesForWriteV2[r]; ok {
+			b.WriteString(e)
+		} else if isDisallowedLiteralV2(r) {
+			fmt.Fprintf(&b, "\\u{%x}", r)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+
+	_, err := w.writer.WriteString(b.String())
+	return err
+}
+
+var escapesForWriteV2 = map[rune]string{
+	'\\': `\\`,
+	'"':  `\"`,
+	'\n': `\n`,
+	'\r': `\r`,
+	'\t': `\t`,
+	'\b': `\b`,
+	'\f': `\f`,
+}
+
+// isDisallowedLiteralV2 checks if the rune has to be escaped in a quoted string in KDL 2.0.0,
+// ie. it is a control character, a new line, a text direction control or a byte order mark.
+func isDisallowedLiteralV2(r rune) bool {
+	if r < 0x80 {
+		return (r < 0x20 && r != '\t') || r == 0x7f
+	}
+	return isNewLine(r) || !isRuneAllowedInBareIdentifierV2(r)
+}
+
+// writeRawString writes a string without escapes,
+// with as many '#' characters around it as needed to not end it early.
+func writeRawString(w *writer, s string) error {
+
+	pounds := 0
+	if w.version == V2 {
+		// At least one is required
+		pounds = 1
+	}
+	for strings.Contains(s, "\""+strings.Repeat("#", pounds)) {
+		pounds++
+	}
+
+	var b strings.Builder
+	b.Grow(len(s) + 2*pounds + 3)
+	if w.version != V2 {
+		b.WriteByte('r')
+	}
+	b.WriteString(strings.Repeat("#", pounds))
+	b.WriteByte('"')
+	b.WriteString(s)
+	b.WriteByte('"')
+	b.WriteString(strings.Repeat("#", pounds))
+
+	_, err := w.writer.WriteString(b.String())
+	return err
+}
+
 func writeBool(w *writer, b bool) error {
+	if w.version == V2 {
+		if err := w.writer.WriteByte('#'); err != nil {
+			return err
+		}
+	}
 	v := bytesFalse[:]
 	if b {
 		v = bytesTrue[:]
@@ -78,6 +167,8 @@ func writeFloatNoExponent(w *writer, f *big.Float) (err error) {
 
 var bigFloatZero = big.NewFloat(0.0)
 
+var errNonFiniteV1 = errors.New("KDL 1.0.0 cannot represent infinities or NaN, write KDL 2.0.0 instead")
+
 func writeFloat(w *writer, f *big.Float) error {
 
 	if f.Cmp(bigFloatZero) == 0 {
@@ -86,7 +177,14 @@ func writeFloat(w *writer, f *big.Float) error {
 	}
 
 	if f.IsInf() {
-		_, err := w.writer.WriteString("Inf")
+		if w.version != V2 {
+			return errNonFiniteV1
+		}
+		text := "#inf"
+		if f.Signbit() {
+			text = "#-inf"
+		}
+		_, err := w.writer.WriteString(text)
 		return err
 	}
 
@@ -125,7 +223,20 @@ func writeFloat(w *writer, f *big.Float) error {
 	return err
 }
 
+func writeNaN(w *writer) error {
+	if w.version != V2 {
+		return errNonFiniteV1
+	}
+	_, err := w.writer.WriteString("#nan")
+	return err
+}
+
 func writeNull(w *writer) error {
+	if w.version == V2 {
+		if err := w.writer.WriteByte('#'); err != nil {
+			return err
+		}
+	}
 	_, err := w.writer.Write(bytesNull[:])
 	return err
 }
@@ -137,6 +248,18 @@ func writeValue(w *writer, v *Value) error {
 		return err
 	}
 
+	if n, ok := v.Notation(); ok {
+		if n.Text != "" {
+			_, err := w.writer.WriteString(n.Text)
+			return err
+		}
+		// A raw string in KDL 2.0.0 cannot span multiple lines, unless it is a multi-line one
+		s := v.StringValue()
+		if n.Raw && (w.version != V2 || strings.IndexFunc(s, isDisallowedLiteralV2) < 0) {
+			return writeRawString(w, s)
+		}
+	}
+
 	switch v.Type {
 	case TypeString:
 		return writeString(w, v.StringValue())
@@ -144,6 +267,8 @@ func writeValue(w *writer, v *Value) error {
 		return writeInteger(w, v.IntegerValue())
 	case TypeFloat:
 		return writeFloat(w, v.FloatValue())
+	case TypeNaN:
+		return writeNaN(w)
 	case TypeBool:
 		return writeBool(w, v.BoolValue())
 	case TypeNull:
@@ -154,7 +279,11 @@ func writeValue(w *writer, v *Value) error {
 }
 
 func writeIdentifier(w *writer, i Identifier) (err error) {
-	if isAllowedBareIdentifier(string(i)) {
+	bare := isAllowedBareIdentifier
+	if w.version == V2 {
+		bare = isAllowedBareIdentifierV2
+	}
+	if bare(string(i)) {
 		_, err = w.writer.WriteString(string(i))
 	} else {
 		err = writeString(w, string(i))
@@ -3,8 +3,10 @@ package kdl
 import "bufio"
 
 type writer struct {
-	writer *bufio.Writer
-	depth  int
+	writer  *bufio.Writer
+	depth   int
+	version Version
+	indent  string // One level of indentation, four spaces if empty.
 }
 
 func writeSpace(w *writer) error {